@@ -1,9 +1,18 @@
 package api
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"aoa-inventory/audit"
 	"aoa-inventory/squareUtils"
 	"aoa-inventory/squareUtils/models"
 	"aoa-inventory/utils"
@@ -13,18 +22,217 @@ import (
 
 var log = utils.NewLogger("API")
 
-func SetupEndpoints(apiGroup *gin.RouterGroup) {
-	apiGroup.GET("/inventory", GetInventory)
-	apiGroup.PUT("/inventory/:sku", UpdateInventoryItem)
+var inventoryBackend squareUtils.InventoryBackend
+
+// maxBatchChanges caps the size of a bulk update request.
+const maxBatchChanges = 1000
+
+// maxInventoryLimit caps the page size of an inventory listing.
+const maxInventoryLimit = 1000
+
+// defaultHistoryLimit and maxHistoryLimit bound the page size of a history request; the maximum
+// is Square's.
+const (
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 1000
+)
+
+func SetupEndpoints(apiGroup *gin.RouterGroup, backend squareUtils.InventoryBackend, audits *audit.Log) {
+	inventoryBackend = backend
+	auditLog = audits
+
+	viewer := requireRole(RoleViewer)
+	stockEditor := requireRole(RoleStockEditor)
+	catalogAdmin := requireRole(RoleCatalogAdmin)
+	admin := requireRole(RoleAdmin)
+
+	apiGroup.GET("/inventory", viewer, GetInventory)
+	// The static route wins, so an item whose SKU is "low-stock" is only found by variation ID.
+	apiGroup.GET("/inventory/low-stock", viewer, GetLowStockInventory)
+	apiGroup.GET("/inventory/:sku", viewer, GetInventoryItem)
+	apiGroup.POST("/inventory", catalogAdmin, CreateInventoryItem)
+	// Catalog fields in an update are checked against the caller's role in the handler.
+	apiGroup.PUT("/inventory/:sku", stockEditor, UpdateInventoryItem)
+	apiGroup.DELETE("/inventory/:sku", catalogAdmin, DeleteInventoryItem)
+	apiGroup.POST("/inventory/:sku/adjust", stockEditor, AdjustInventoryItem)
+	apiGroup.POST("/inventory/:sku/image", catalogAdmin, UploadInventoryItemImage)
+	apiGroup.GET("/inventory/:sku/history", viewer, GetInventoryHistory)
+	apiGroup.POST("/inventory/batch", stockEditor, BatchUpdateInventory)
+	apiGroup.GET("/variations/:variationId", viewer, GetInventoryItemByVariationID)
+	apiGroup.GET("/items", viewer, GetItems)
+	apiGroup.GET("/locations", viewer, GetLocations)
+	apiGroup.GET("/audit", admin, GetAudit)
 }
 
+// GetInventory lists inventory at the location given by the optional "location" query
+// parameter, or summed across all locations when it is "all". The optional "states" query
+// parameter adds a per-state breakdown, e.g. ?states=IN_STOCK,WASTE. Archived items are left out
+// unless includeArchived is true. Items can be filtered, sorted with "sort" (prefix "-" for
+// descending) and paged with "limit" and "cursor"; the X-Total-Count header holds the number of
+// matching items and X-Next-Cursor the cursor of the next page.
 func GetInventory(ctx *gin.Context) {
 	log.Println("Getting inventory...")
 
-	// inventory := squareUtils.LoadSampleInventory("data.json")
-	inventory := squareUtils.LoadInventory()
+	query, ok := inventoryQuery(ctx)
+	if !ok {
+		return
+	}
+
+	filter, ok := inventoryFilter(ctx)
+	if !ok {
+		return
+	}
+
+	sortBy, descending := squareUtils.SortByName, false
+	if rawSort := ctx.Query("sort"); rawSort != "" {
+		sortBy, descending = strings.TrimPrefix(rawSort, "-"), strings.HasPrefix(rawSort, "-")
+		if !slices.Contains(squareUtils.SortKeys, sortBy) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "sort must be one of " + strings.Join(squareUtils.SortKeys, ", ")})
+			return
+		}
+	}
+
+	after, ok := cursorQuery(ctx, sortBy, descending)
+	if !ok {
+		return
+	}
+
+	limit := 0
+	if ctx.Query("limit") != "" {
+		if limit, ok = limitQuery(ctx, 0, maxInventoryLimit); !ok {
+			return
+		}
+	}
+
+	inventory, err := inventoryBackend.ListInventory(ctx.Request.Context(), query)
+	if err != nil {
+		respondWithBackendError(ctx, err, "could not load inventory")
+		return
+	}
+
+	inventory = squareUtils.FilterInventory(inventory, filter)
+	squareUtils.SortInventory(inventory, sortBy, descending)
+
+	ctx.Header("X-Total-Count", strconv.Itoa(len(inventory)))
+
+	page := inventory
+	if after != nil {
+		page = squareUtils.InventoryAfter(inventory, *after, sortBy, descending)
+	}
+	if limit > 0 && limit < len(page) {
+		page = page[:limit]
+		ctx.Header("X-Next-Cursor", encodeCursor(sortBy, descending, page[limit-1]))
+	}
+
+	ctx.JSON(http.StatusOK, page)
+}
+
+// GetLowStockInventory lists the items at a single location that have a low-quantity alert and
+// are at or below its threshold.
+func GetLowStockInventory(ctx *gin.Context) {
+	query, ok := inventoryQuery(ctx)
+	if !ok {
+		return
+	}
+
+	if query.LocationID == squareUtils.AllLocations {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "low stock is reported for a single location"})
+		return
+	}
+
+	// A tracked variation that was never counted has no stock, which is as low as it gets.
+	query.IncludeUncounted = true
+
+	inventory, err := inventoryBackend.ListInventory(ctx.Request.Context(), query)
+	if err != nil {
+		respondWithBackendError(ctx, err, "could not load inventory")
+		return
+	}
+
+	lowStock := []models.InventoryItem{}
+	for _, item := range inventory {
+		if squareUtils.IsLowStock(item) {
+			lowStock = append(lowStock, item)
+		}
+	}
+
+	ctx.JSON(http.StatusOK, lowStock)
+}
+
+// GetInventoryItem returns the item with the SKU, reading only its own catalog objects and count.
+// It takes the location and states query parameters of GetInventory, and archived items are
+// returned too.
+func GetInventoryItem(ctx *gin.Context) {
+	sku := ctx.Param("sku")
+	if sku == "" {
+		log.Println("ERROR: No SKU provided")
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "sku is required"})
+		return
+	}
+
+	query, ok := inventoryQuery(ctx)
+	if !ok {
+		return
+	}
+
+	item, err := inventoryBackend.GetInventoryItem(ctx.Request.Context(), sku, query)
+	if err != nil {
+		respondWithBackendError(ctx, err, "could not load inventory item")
+		return
+	}
+
+	respondWithItem(ctx, http.StatusOK, item)
+}
+
+// GetInventoryItemByVariationID is GetInventoryItem for a Square variation ID, the item's "id".
+func GetInventoryItemByVariationID(ctx *gin.Context) {
+	variationID := ctx.Param("variationId")
+	if variationID == "" {
+		log.Println("ERROR: No variation ID provided")
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "variation ID is required"})
+		return
+	}
+
+	query, ok := inventoryQuery(ctx)
+	if !ok {
+		return
+	}
+
+	item, err := inventoryBackend.GetInventoryItemByVariationID(ctx.Request.Context(), variationID, query)
+	if err != nil {
+		respondWithBackendError(ctx, err, "could not load inventory item")
+		return
+	}
+
+	respondWithItem(ctx, http.StatusOK, item)
+}
+
+// CreateInventoryItem creates an item with one or more variations and records their opening
+// stock at the location, responding with the new inventory item for each variation.
+func CreateInventoryItem(ctx *gin.Context) {
+	locationID, ok := writeLocation(ctx)
+	if !ok {
+		return
+	}
+
+	var newItem models.NewInventoryItem
+	if err := ctx.ShouldBindJSON(&newItem); err != nil {
+		log.Printf("ERROR: Failed to bind request body: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	reqCtx, trail := auditContext(ctx)
+	createdItems, err := inventoryBackend.CreateInventoryItem(reqCtx, locationID, &newItem)
+	for _, sku := range newItemSKUs(&newItem) {
+		recordAudit(ctx, trail, audit.ActionCreate, sku, err)
+	}
+	if err != nil {
+		respondWithBackendError(ctx, err, "could not create inventory item")
+		return
+	}
 
-	ctx.JSON(http.StatusOK, inventory)
+	ctx.JSON(http.StatusCreated, createdItems)
 }
 
 func UpdateInventoryItem(ctx *gin.Context) {
@@ -35,6 +243,11 @@ func UpdateInventoryItem(ctx *gin.Context) {
 		return
 	}
 
+	locationID, ok := writeLocation(ctx)
+	if !ok {
+		return
+	}
+
 	var updatePayload models.InventoryItemUpdate
 	if err := ctx.ShouldBindJSON(&updatePayload); err != nil {
 		log.Printf("ERROR: Failed to bind request body: %v", err)
@@ -42,17 +255,533 @@ func UpdateInventoryItem(ctx *gin.Context) {
 		return
 	}
 
-	savedItem, err := squareUtils.UpdateInventoryItem("", sku, &updatePayload)
+	// An If-Match header takes precedence over an expectedVersion in the body.
+	if ifMatch := ctx.GetHeader("If-Match"); ifMatch != "" && ifMatch != "*" {
+		version := strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`)
+		updatePayload.ExpectedVersion = &version
+	}
+
+	if !checkUpdateFields(ctx, locationID, sku, &updatePayload) {
+		return
+	}
+
+	reqCtx, trail := auditContext(ctx)
+	savedItem, err := inventoryBackend.UpdateInventoryItem(reqCtx, locationID, sku, &updatePayload)
+	recordAudit(ctx, trail, audit.ActionUpdate, sku, err)
 	if err != nil {
-		if errors.Is(err, squareUtils.ErrInventoryItemNotFound) {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "item not found"})
+		respondWithBackendError(ctx, err, "could not update inventory item")
+		return
+	}
+
+	respondWithItem(ctx, http.StatusOK, savedItem)
+}
+
+// DeleteInventoryItem archives the item by default, so it can be restored by updating it with
+// "archived": false. With ?hard=true the SKU is deleted from the catalog instead.
+func DeleteInventoryItem(ctx *gin.Context) {
+	sku := ctx.Param("sku")
+	if sku == "" {
+		log.Println("ERROR: No SKU provided")
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "sku is required"})
+		return
+	}
+
+	hard, ok := boolQuery(ctx, "hard")
+	if !ok {
+		return
+	}
+
+	reqCtx, trail := auditContext(ctx)
+	err := inventoryBackend.DeleteInventoryItem(reqCtx, sku, hard)
+	recordAudit(ctx, trail, audit.ActionDelete, sku, err)
+	if err != nil {
+		respondWithBackendError(ctx, err, "could not delete inventory item")
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// AdjustInventoryItem changes stock by a signed delta in one Square adjustment, so concurrent
+// adjustments do not overwrite each other the way absolute updates can.
+func AdjustInventoryItem(ctx *gin.Context) {
+	sku := ctx.Param("sku")
+	if sku == "" {
+		log.Println("ERROR: No SKU provided")
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "sku is required"})
+		return
+	}
+
+	locationID, ok := writeLocation(ctx)
+	if !ok {
+		return
+	}
+
+	var adjustment models.InventoryAdjustment
+	if err := ctx.ShouldBindJSON(&adjustment); err != nil {
+		log.Printf("ERROR: Failed to bind request body: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	// The backend rejects a zero delta along with the other invalid adjustments.
+	if adjustment.Delta == nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "delta is required"})
+		return
+	}
+
+	reason := squareUtils.AdjustmentReason("")
+	if adjustment.Reason != nil {
+		parsed, err := squareUtils.ParseAdjustmentReason(*adjustment.Reason)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		reason = parsed
+	}
 
-		log.Printf("ERROR: Failed to update inventory item: %v", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not update inventory item"})
+	reqCtx, trail := auditContext(ctx)
+	savedItem, err := inventoryBackend.AdjustInventoryItem(reqCtx, locationID, sku, *adjustment.Delta, reason)
+	recordAudit(ctx, trail, audit.ActionAdjust, sku, err)
+	if err != nil {
+		respondWithBackendError(ctx, err, "could not adjust inventory item")
+		return
+	}
+
+	respondWithItem(ctx, http.StatusOK, savedItem)
+}
+
+// UploadInventoryItemImage attaches the multipart "image" file to the SKU's item, or to its
+// variation when the "attachTo" form field is "variation". A "primary" field of true makes it
+// the image shown for the item.
+func UploadInventoryItemImage(ctx *gin.Context) {
+	sku := ctx.Param("sku")
+	if sku == "" {
+		log.Println("ERROR: No SKU provided")
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "sku is required"})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, savedItem)
+	locationID, ok := writeLocation(ctx)
+	if !ok {
+		return
+	}
+
+	// Leave room for the multipart headers and the other form fields.
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, squareUtils.MaxImageSize+1<<20)
+
+	fileHeader, err := ctx.FormFile("image")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": squareUtils.ErrImageTooLarge.Error()})
+			return
+		}
+		log.Printf("ERROR: Failed to read image upload: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "an image file is required"})
+		return
+	}
+
+	upload := &squareUtils.ImageUpload{Filename: fileHeader.Filename}
+	switch attachTo := ctx.PostForm("attachTo"); attachTo {
+	case "", "item":
+	case "variation":
+		upload.ForVariation = true
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "attachTo must be item or variation"})
+		return
+	}
+
+	if rawPrimary := ctx.PostForm("primary"); rawPrimary != "" {
+		if upload.Primary, err = strconv.ParseBool(rawPrimary); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "primary must be true or false"})
+			return
+		}
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		log.Printf("ERROR: Failed to open image upload: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "could not read the image file"})
+		return
+	}
+	defer file.Close()
+
+	if upload.Data, err = io.ReadAll(file); err != nil {
+		log.Printf("ERROR: Failed to read image upload: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "could not read the image file"})
+		return
+	}
+
+	reqCtx, trail := auditContext(ctx)
+	savedItem, err := inventoryBackend.UploadInventoryItemImage(reqCtx, locationID, sku, upload)
+	recordAudit(ctx, trail, audit.ActionImage, sku, err)
+	if err != nil {
+		respondWithBackendError(ctx, err, "could not upload image")
+		return
+	}
+
+	respondWithItem(ctx, http.StatusOK, savedItem)
+}
+
+// GetInventoryHistory pages through the stock changes of a SKU, oldest first. The optional "from"
+// and "to" query parameters (RFC 3339) limit the date range, and "cursor" and "limit" page.
+func GetInventoryHistory(ctx *gin.Context) {
+	sku := ctx.Param("sku")
+	if sku == "" {
+		log.Println("ERROR: No SKU provided")
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "sku is required"})
+		return
+	}
+
+	since, until, ok := timeRangeQuery(ctx)
+	if !ok {
+		return
+	}
+
+	limit, ok := limitQuery(ctx, defaultHistoryLimit, maxHistoryLimit)
+	if !ok {
+		return
+	}
+
+	query := squareUtils.HistoryQuery{
+		LocationID: ctx.Query("location"),
+		Since:      since,
+		Until:      until,
+		Cursor:     ctx.Query("cursor"),
+		Limit:      limit,
+	}
+
+	history, err := inventoryBackend.InventoryHistory(ctx.Request.Context(), sku, query)
+	if err != nil {
+		respondWithBackendError(ctx, err, "could not load inventory history")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, history)
+}
+
+// BatchUpdateInventory applies an array of {sku, currentStock|delta, reason} changes at one
+// location and reports the outcome of each, so one bad SKU does not fail the whole request.
+func BatchUpdateInventory(ctx *gin.Context) {
+	locationID, ok := writeLocation(ctx)
+	if !ok {
+		return
+	}
+
+	var changes []models.StockChange
+	if err := ctx.ShouldBindJSON(&changes); err != nil {
+		log.Printf("ERROR: Failed to bind request body: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	if len(changes) == 0 || len(changes) > maxBatchChanges {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("between 1 and %d changes are required", maxBatchChanges)})
+		return
+	}
+
+	reqCtx, trail := auditContext(ctx)
+	outcomes, err := inventoryBackend.BatchUpdateInventory(reqCtx, locationID, changes)
+	if err != nil {
+		for _, change := range changes {
+			recordAudit(ctx, trail, audit.ActionBatch, change.SKU, err)
+		}
+		respondWithBackendError(ctx, err, "could not update inventory")
+		return
+	}
+
+	for _, outcome := range outcomes {
+		recordAudit(ctx, trail, audit.ActionBatch, outcome.SKU, outcome.Err)
+	}
+
+	results := make([]models.StockChangeResult, len(outcomes))
+	failed := 0
+	for i, outcome := range outcomes {
+		results[i] = models.StockChangeResult{SKU: outcome.SKU, Status: http.StatusOK, Item: outcome.Item}
+		if outcome.Err != nil {
+			results[i].Status, results[i].Error = backendErrorStatus(outcome.Err, "could not update inventory item")
+			failed++
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"results": results, "succeeded": len(results) - failed, "failed": failed})
+}
+
+// GetItems lists catalog items with their variations nested. It takes the same query
+// parameters as GetInventory.
+func GetItems(ctx *gin.Context) {
+	query, ok := inventoryQuery(ctx)
+	if !ok {
+		return
+	}
+
+	items, err := inventoryBackend.ListItems(ctx.Request.Context(), query)
+	if err != nil {
+		respondWithBackendError(ctx, err, "could not load items")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, items)
+}
+
+func GetLocations(ctx *gin.Context) {
+	locations, err := inventoryBackend.ListLocations(ctx.Request.Context())
+	if err != nil {
+		respondWithBackendError(ctx, err, "could not load locations")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, locations)
+}
+
+// inventoryQuery reads the "location", "states" and "includeArchived" query parameters of a
+// read request.
+func inventoryQuery(ctx *gin.Context) (squareUtils.InventoryQuery, bool) {
+	query := squareUtils.InventoryQuery{LocationID: ctx.Query("location")}
+
+	includeArchived, ok := boolQuery(ctx, "includeArchived")
+	if !ok {
+		return query, false
+	}
+	query.IncludeArchived = includeArchived
+
+	if rawStates := ctx.Query("states"); rawStates != "" {
+		states, err := squareUtils.ParseInventoryStates(rawStates)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return query, false
+		}
+		query.States = states
+	}
+
+	return query, true
+}
+
+// timeRangeQuery parses the optional "from" and "to" query parameters as RFC 3339 timestamps,
+// responding with 400 Bad Request if either is invalid or the range is reversed.
+func timeRangeQuery(ctx *gin.Context) (time.Time, time.Time, bool) {
+	var since, until time.Time
+	for name, bound := range map[string]*time.Time{"from": &since, "to": &until} {
+		if raw := ctx.Query(name); raw != "" {
+			parsed, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": name + " must be an RFC 3339 timestamp"})
+				return time.Time{}, time.Time{}, false
+			}
+			*bound = parsed
+		}
+	}
+
+	if !since.IsZero() && !until.IsZero() && until.Before(since) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "to must not be before from"})
+		return time.Time{}, time.Time{}, false
+	}
+
+	return since, until, true
+}
+
+// limitQuery parses the optional "limit" query parameter, responding with 400 Bad Request unless
+// it is between 1 and maxLimit.
+func limitQuery(ctx *gin.Context, defaultLimit, maxLimit int) (int, bool) {
+	rawLimit := ctx.Query("limit")
+	if rawLimit == "" {
+		return defaultLimit, true
+	}
+
+	limit, err := strconv.Atoi(rawLimit)
+	if err != nil || limit < 1 || limit > maxLimit {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxLimit)})
+		return 0, false
+	}
+	return limit, true
+}
+
+// inventoryFilter parses the filter query parameters of GetInventory, responding with 400 Bad
+// Request if one is invalid.
+func inventoryFilter(ctx *gin.Context) (squareUtils.InventoryFilter, bool) {
+	filter := squareUtils.InventoryFilter{
+		Category:          ctx.Query("category"),
+		ReportingCategory: ctx.Query("reportingCategory"),
+		Search:            ctx.Query("q"),
+	}
+
+	for name, bound := range map[string]**int{"minStock": &filter.MinStock, "maxStock": &filter.MaxStock} {
+		if raw := ctx.Query(name); raw != "" {
+			value, err := strconv.Atoi(raw)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": name + " must be a whole number"})
+				return filter, false
+			}
+			*bound = &value
+		}
+	}
+
+	inStock, ok := boolQuery(ctx, "inStock")
+	if !ok {
+		return filter, false
+	}
+	filter.InStockOnly = inStock
+
+	return filter, true
+}
+
+// inventoryCursor is where a page of inventory ended: the sort it was read with and the sort
+// key of its last item. Resuming after that item rather than at an offset keeps items created or
+// removed between pages from shifting the rest.
+type inventoryCursor struct {
+	Sort          string `json:"sort"`
+	Name          string `json:"name"`
+	VariationName string `json:"variationName"`
+	SKU           string `json:"sku"`
+	Stock         int    `json:"stock"`
+	Category      string `json:"category"`
+}
+
+// cursorQuery returns the item the "cursor" query parameter says the previous page ended at, or
+// nil when there is none. The cursor must come from a page read with the same sort.
+func cursorQuery(ctx *gin.Context, sortBy string, descending bool) (*models.InventoryItem, bool) {
+	rawCursor := ctx.Query("cursor")
+	if rawCursor == "" {
+		return nil, true
+	}
+
+	var cursor inventoryCursor
+	decoded, err := base64.RawURLEncoding.DecodeString(rawCursor)
+	if err == nil {
+		err = json.Unmarshal(decoded, &cursor)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+		return nil, false
+	}
+
+	if cursor.Sort != sortParam(sortBy, descending) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "cursor is for a different sort"})
+		return nil, false
+	}
+
+	return &models.InventoryItem{
+		Name:          cursor.Name,
+		VariationName: cursor.VariationName,
+		SKU:           cursor.SKU,
+		CurrentStock:  cursor.Stock,
+		Category:      cursor.Category,
+	}, true
+}
+
+// encodeCursor returns an opaque cursor for the page after last.
+func encodeCursor(sortBy string, descending bool, last models.InventoryItem) string {
+	data, _ := json.Marshal(inventoryCursor{
+		Sort:          sortParam(sortBy, descending),
+		Name:          last.Name,
+		VariationName: last.VariationName,
+		SKU:           last.SKU,
+		Stock:         last.CurrentStock,
+		Category:      last.Category,
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func sortParam(sortBy string, descending bool) string {
+	if descending {
+		return "-" + sortBy
+	}
+	return sortBy
+}
+
+// newItemSKUs returns the SKUs a create request asks for, one per variation.
+func newItemSKUs(newItem *models.NewInventoryItem) []string {
+	if len(newItem.Variations) == 0 {
+		return []string{newItem.SKU}
+	}
+
+	skus := make([]string, len(newItem.Variations))
+	for i, variation := range newItem.Variations {
+		skus[i] = variation.SKU
+	}
+	return skus
+}
+
+// boolQuery reads an optional boolean query parameter, responding with 400 if it is not one.
+func boolQuery(ctx *gin.Context, name string) (bool, bool) {
+	raw := ctx.Query(name)
+	if raw == "" {
+		return false, true
+	}
+
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": name + " must be true or false"})
+		return false, false
+	}
+
+	return value, true
+}
+
+// writeLocation reads the optional "location" query parameter of a stock change, which must
+// name a single location.
+func writeLocation(ctx *gin.Context) (string, bool) {
+	locationID := ctx.Query("location")
+	if locationID == squareUtils.AllLocations {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "stock can only be changed at a single location"})
+		return "", false
+	}
+
+	return locationID, true
+}
+
+// respondWithItem writes a single item, exposing its version as an ETag for If-Match.
+func respondWithItem(ctx *gin.Context, status int, item *models.InventoryItem) {
+	if item.Version != "" {
+		ctx.Header("ETag", `"`+item.Version+`"`)
+	}
+
+	ctx.JSON(status, item)
+}
+
+// respondWithBackendError maps backend errors to HTTP responses, logging unexpected ones.
+func respondWithBackendError(ctx *gin.Context, err error, message string) {
+	var conflict *squareUtils.StockConflictError
+	if errors.As(err, &conflict) {
+		if conflict.Item.Version != "" {
+			ctx.Header("ETag", `"`+conflict.Item.Version+`"`)
+		}
+		ctx.JSON(http.StatusConflict, gin.H{"error": conflict.Error(), "item": conflict.Item})
+		return
+	}
+
+	if wait, ok := squareRateLimited(err); ok {
+		setRetryAfter(ctx, wait)
+	}
+
+	status, errorMessage := backendErrorStatus(err, message)
+	ctx.JSON(status, gin.H{"error": errorMessage})
+}
+
+// backendErrorStatus returns the HTTP status and client-facing message for a backend error.
+func backendErrorStatus(err error, message string) (int, string) {
+	switch {
+	case errors.Is(err, squareUtils.ErrInventoryItemNotFound):
+		return http.StatusNotFound, "item not found"
+	case errors.Is(err, squareUtils.ErrLocationNotFound):
+		return http.StatusNotFound, "location not found"
+	case errors.Is(err, squareUtils.ErrInvalidAdjustment), errors.Is(err, squareUtils.ErrInvalidItemUpdate),
+		errors.Is(err, squareUtils.ErrInvalidNewItem), errors.Is(err, squareUtils.ErrInvalidImage):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, squareUtils.ErrImageTooLarge):
+		return http.StatusRequestEntityTooLarge, err.Error()
+	case errors.Is(err, squareUtils.ErrNotSupported):
+		return http.StatusNotImplemented, err.Error()
+	case errors.Is(err, squareUtils.ErrCatalogConflict), errors.Is(err, squareUtils.ErrDuplicateSKU):
+		return http.StatusConflict, err.Error()
+	default:
+		if _, ok := squareRateLimited(err); ok {
+			log.Printf("ERROR: %s: %v", message, err)
+			return http.StatusTooManyRequests, "square rate limit reached, please retry later"
+		}
+
+		log.Printf("ERROR: %s: %v", message, err)
+		return http.StatusInternalServerError, message
+	}
 }
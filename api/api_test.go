@@ -0,0 +1,120 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"aoa-inventory/squareUtils"
+	"aoa-inventory/squareUtils/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newQueryContext(rawQuery string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/?"+rawQuery, nil)
+	return ctx, rec
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	last := models.InventoryItem{Name: "Almond Croissant", VariationName: "Large", SKU: "BAK-002", CurrentStock: 4, Category: "Bakery"}
+
+	for _, descending := range []bool{false, true} {
+		ctx, _ := newQueryContext("cursor=" + encodeCursor(squareUtils.SortByStock, descending, last))
+
+		after, ok := cursorQuery(ctx, squareUtils.SortByStock, descending)
+		if !ok {
+			t.Fatalf("descending=%t: cursor was rejected", descending)
+		}
+		if after.Name != last.Name || after.VariationName != last.VariationName || after.SKU != last.SKU ||
+			after.CurrentStock != last.CurrentStock || after.Category != last.Category {
+			t.Errorf("descending=%t: cursor item = %+v, want the sort key of %+v", descending, *after, last)
+		}
+	}
+}
+
+func TestCursorQueryWithoutCursor(t *testing.T) {
+	ctx, _ := newQueryContext("")
+
+	after, ok := cursorQuery(ctx, squareUtils.SortByName, false)
+	if !ok || after != nil {
+		t.Errorf("cursorQuery = %v, %t; want no item", after, ok)
+	}
+}
+
+func TestCursorQueryRejects(t *testing.T) {
+	cursor := encodeCursor(squareUtils.SortByName, false, models.InventoryItem{SKU: "LAT-001"})
+
+	tests := map[string]struct {
+		cursor     string
+		sortBy     string
+		descending bool
+	}{
+		"not base64":         {"!!!", squareUtils.SortByName, false},
+		"not json":           {base64.RawURLEncoding.EncodeToString([]byte("offset:3")), squareUtils.SortByName, false},
+		"different sort":     {cursor, squareUtils.SortBySKU, false},
+		"different ordering": {cursor, squareUtils.SortByName, true},
+	}
+
+	for name, tt := range tests {
+		ctx, rec := newQueryContext("cursor=" + tt.cursor)
+
+		if _, ok := cursorQuery(ctx, tt.sortBy, tt.descending); ok {
+			t.Errorf("%s: cursor was accepted", name)
+		}
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", name, rec.Code)
+		}
+	}
+}
+
+func TestCursorResumesAfterRemovedItem(t *testing.T) {
+	items := []models.InventoryItem{
+		{Name: "Apple", SKU: "A"},
+		{Name: "Banana", SKU: "B"},
+		{Name: "Cherry", SKU: "C"},
+		{Name: "Damson", SKU: "D"},
+	}
+	ctx, _ := newQueryContext("cursor=" + encodeCursor(squareUtils.SortByName, false, items[1]))
+	after, _ := cursorQuery(ctx, squareUtils.SortByName, false)
+
+	// Banana, the last item of the first page, was removed and a new item added before it.
+	current := []models.InventoryItem{{Name: "Apricot", SKU: "AP"}, items[0], items[2], items[3]}
+	squareUtils.SortInventory(current, squareUtils.SortByName, false)
+
+	page := squareUtils.InventoryAfter(current, *after, squareUtils.SortByName, false)
+	if len(page) != 2 || page[0].SKU != "C" || page[1].SKU != "D" {
+		t.Errorf("next page = %v, want Cherry and Damson", page)
+	}
+}
+
+func TestGetLowStockInventoryIncludesUncounted(t *testing.T) {
+	server, _ := useFakeBackend(t)
+	server.AddLocation("LOC_SECOND", "Second Shop")
+
+	// Nothing was ever counted at the new location, so Square has no count for the latte there.
+	alert := &models.InventoryAlert{Type: "LOW_QUANTITY", Threshold: 2}
+	if _, err := inventoryBackend.UpdateInventoryItem(t.Context(), "LOC_SECOND", "LAT-001", &models.InventoryItemUpdate{InventoryAlert: alert}); err != nil {
+		t.Fatalf("UpdateInventoryItem: %v", err)
+	}
+
+	ctx, rec := newQueryContext("location=LOC_SECOND")
+	GetLowStockInventory(ctx)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+
+	var lowStock []models.InventoryItem
+	if err := json.Unmarshal(rec.Body.Bytes(), &lowStock); err != nil {
+		t.Fatal(err)
+	}
+	if len(lowStock) != 1 || lowStock[0].SKU != "LAT-001" || lowStock[0].CurrentStock != 0 {
+		t.Errorf("low stock = %+v, want LAT-001 with no stock", lowStock)
+	}
+}
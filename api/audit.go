@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"aoa-inventory/audit"
+	"aoa-inventory/squareUtils/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+var auditLog *audit.Log
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// GetAudit lists audit records, newest first. The optional "sku" and "user" query parameters
+// select one SKU or caller, "from" and "to" (RFC 3339) limit the time range and "limit" caps the
+// number returned.
+func GetAudit(ctx *gin.Context) {
+	since, until, ok := timeRangeQuery(ctx)
+	if !ok {
+		return
+	}
+
+	limit, ok := limitQuery(ctx, defaultAuditLimit, maxAuditLimit)
+	if !ok {
+		return
+	}
+
+	records, err := auditLog.Query(audit.Filter{
+		SKU:   ctx.Query("sku"),
+		User:  ctx.Query("user"),
+		Since: since,
+		Until: until,
+		Limit: limit,
+	})
+	if err != nil {
+		log.Printf("ERROR: Failed to read audit log: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not read audit log"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, records)
+}
+
+// auditContext returns the request context with a trail for the backend to note its writes on.
+func auditContext(ctx *gin.Context) (context.Context, *audit.Trail) {
+	return audit.WithTrail(ctx.Request.Context())
+}
+
+// recordAudit appends the trail's record for the SKU with the caller and the result of the
+// write. A failure to append is logged rather than returned, since the write itself is done.
+func recordAudit(ctx *gin.Context, trail *audit.Trail, action, sku string, err error) {
+	record := trail.Update(sku, func(record *models.AuditRecord) {
+		record.Time = time.Now().UTC()
+		record.User = auditUser(ctx)
+		record.Action = action
+		record.Result, record.Error = audit.ResultOK, ""
+		if err != nil {
+			record.Result, record.Error = audit.ResultError, err.Error()
+		}
+	})
+
+	if err := auditLog.Append(&record); err != nil {
+		log.Printf("ERROR: Failed to write audit record for sku %s: %v", sku, err)
+	}
+}
+
+// auditUser identifies the caller in audit records: the authenticated caller, or the client
+// address when authentication is disabled.
+func auditUser(ctx *gin.Context) string {
+	if caller, ok := CallerFromContext(ctx); ok {
+		return caller.ID
+	}
+	return ctx.ClientIP()
+}
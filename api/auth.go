@@ -0,0 +1,234 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	apiKeyHeader = "X-API-Key"
+
+	// callerKey is where the authenticated Caller is stored on the gin context.
+	callerKey = "caller"
+
+	// jwtLeeway tolerates clock skew between the token issuer and this server.
+	jwtLeeway = time.Minute
+)
+
+const (
+	AuthMethodAPIKey = "api_key"
+	AuthMethodJWT    = "jwt"
+)
+
+// AuthConfig lists the credentials RequireAuth accepts. APIKeys maps each key to the caller
+// using it. Tokens are only accepted when JWTSecret is set; JWTIssuer and JWTAudience, when set,
+// must match the token's iss and aud claims.
+type AuthConfig struct {
+	APIKeys     map[string]APIKey
+	JWTSecret   string
+	JWTIssuer   string
+	JWTAudience string
+}
+
+// APIKey is the caller a static API key stands for.
+type APIKey struct {
+	Name string
+	Role Role
+}
+
+// Caller is the authenticated identity behind a request.
+type Caller struct {
+	ID     string
+	Method string
+	Role   Role
+}
+
+var errInvalidToken = errors.New("invalid token")
+
+// jwtAlgorithms are the HMAC algorithms accepted in a token's alg header.
+var jwtAlgorithms = map[string]func() hash.Hash{
+	"HS256": sha256.New,
+	"HS384": sha512.New384,
+	"HS512": sha512.New,
+}
+
+// jwtClaims is the subset of registered claims checked on a token.
+type jwtClaims struct {
+	Subject   string          `json:"sub"`
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *int64          `json:"exp"`
+	NotBefore *int64          `json:"nbf"`
+
+	// Role or Roles name the caller's roles; the highest known one applies.
+	Role  string   `json:"role"`
+	Roles []string `json:"roles"`
+}
+
+// RequireAuth rejects requests without a valid API key or bearer token with 401 Unauthorized,
+// and stores the Caller on the context of those it lets through. An API key is sent in the
+// X-API-Key header or as the bearer token; any other bearer token must be a JWT signed with
+// JWTSecret.
+func RequireAuth(authConfig AuthConfig) gin.HandlerFunc {
+	// Keys are compared by hash so the lookup takes the same time however much of a key matches.
+	keys := map[[sha256.Size]byte]APIKey{}
+	for key, apiKey := range authConfig.APIKeys {
+		keys[sha256.Sum256([]byte(key))] = apiKey
+	}
+
+	return func(ctx *gin.Context) {
+		credential := ctx.GetHeader(apiKeyHeader)
+		if credential == "" {
+			scheme, token, _ := strings.Cut(ctx.GetHeader("Authorization"), " ")
+			if strings.EqualFold(scheme, "Bearer") {
+				credential = strings.TrimSpace(token)
+			}
+		}
+
+		if credential == "" {
+			rejectUnauthenticated(ctx, "authentication required")
+			return
+		}
+
+		if apiKey, ok := keys[sha256.Sum256([]byte(credential))]; ok {
+			ctx.Set(callerKey, Caller{ID: apiKey.Name, Method: AuthMethodAPIKey, Role: apiKey.Role})
+			ctx.Next()
+			return
+		}
+
+		if authConfig.JWTSecret == "" || ctx.GetHeader(apiKeyHeader) != "" {
+			log.Printf("ERROR: Rejected request to %s with an unknown API key", ctx.Request.URL.Path)
+			rejectUnauthenticated(ctx, "invalid API key")
+			return
+		}
+
+		claims, err := verifyJWT(credential, authConfig, time.Now())
+		if err != nil {
+			log.Printf("ERROR: Rejected request to %s with an invalid token: %v", ctx.Request.URL.Path, err)
+			rejectUnauthenticated(ctx, "invalid token")
+			return
+		}
+
+		ctx.Set(callerKey, Caller{ID: claims.Subject, Method: AuthMethodJWT, Role: claims.role()})
+		ctx.Next()
+	}
+}
+
+// CallerFromContext returns the caller RequireAuth authenticated, if any.
+func CallerFromContext(ctx *gin.Context) (Caller, bool) {
+	value, _ := ctx.Get(callerKey)
+	caller, ok := value.(Caller)
+	return caller, ok
+}
+
+func rejectUnauthenticated(ctx *gin.Context, message string) {
+	ctx.Header("WWW-Authenticate", `Bearer realm="api"`)
+	ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": message})
+}
+
+// verifyJWT checks the token's HMAC signature and its exp, nbf, iss and aud claims, and returns
+// the claims. An expiry is required so no token is valid forever, and a subject since it
+// identifies the caller.
+func verifyJWT(token string, authConfig AuthConfig, now time.Time) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: expected 3 segments", errInvalidToken)
+	}
+
+	var header struct {
+		Algorithm string `json:"alg"`
+	}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+
+	newHash, ok := jwtAlgorithms[header.Algorithm]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported alg %q", errInvalidToken, header.Algorithm)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", errInvalidToken)
+	}
+
+	mac := hmac.New(newHash, []byte(authConfig.JWTSecret))
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if subtle.ConstantTimeCompare(mac.Sum(nil), signature) != 1 {
+		return nil, fmt.Errorf("%w: signature mismatch", errInvalidToken)
+	}
+
+	var claims jwtClaims
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+
+	if claims.ExpiresAt == nil {
+		return nil, fmt.Errorf("%w: missing exp", errInvalidToken)
+	}
+	if now.After(time.Unix(*claims.ExpiresAt, 0).Add(jwtLeeway)) {
+		return nil, fmt.Errorf("%w: expired", errInvalidToken)
+	}
+	if claims.NotBefore != nil && now.Add(jwtLeeway).Before(time.Unix(*claims.NotBefore, 0)) {
+		return nil, fmt.Errorf("%w: not valid yet", errInvalidToken)
+	}
+	if authConfig.JWTIssuer != "" && claims.Issuer != authConfig.JWTIssuer {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", errInvalidToken, claims.Issuer)
+	}
+	if authConfig.JWTAudience != "" && !slices.Contains(claims.audiences(), authConfig.JWTAudience) {
+		return nil, fmt.Errorf("%w: unexpected audience", errInvalidToken)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing sub", errInvalidToken)
+	}
+
+	return &claims, nil
+}
+
+func decodeJWTSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return fmt.Errorf("%w: malformed segment", errInvalidToken)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidToken, err)
+	}
+	return nil
+}
+
+// role returns the highest role the token names, or RoleViewer when it names none this service
+// knows.
+func (c *jwtClaims) role() Role {
+	highest := RoleViewer
+	for _, name := range append([]string{c.Role}, c.Roles...) {
+		if role, err := ParseRole(name); err == nil && role > highest {
+			highest = role
+		}
+	}
+	return highest
+}
+
+// audiences returns the aud claim, which may be a single string or an array of them.
+func (c *jwtClaims) audiences() []string {
+	var single string
+	if json.Unmarshal(c.Audience, &single) == nil {
+		return []string{single}
+	}
+
+	var many []string
+	json.Unmarshal(c.Audience, &many)
+	return many
+}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testJWTSecret = "test-secret"
+
+// signJWT builds a token with the given alg header and claims, signed with secret when alg is
+// one of the supported HMAC algorithms.
+func signJWT(t *testing.T, alg, secret string, claims map[string]any) string {
+	t.Helper()
+
+	encode := func(v any) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	unsigned := encode(map[string]string{"alg": alg, "typ": "JWT"}) + "." + encode(claims)
+
+	newHash, ok := jwtAlgorithms[alg]
+	if !ok {
+		return unsigned + "."
+	}
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func validClaims(now time.Time) map[string]any {
+	return map[string]any{"sub": "alice", "exp": now.Add(time.Hour).Unix()}
+}
+
+func TestVerifyJWTAlgorithms(t *testing.T) {
+	now := time.Now()
+	authConfig := AuthConfig{JWTSecret: testJWTSecret}
+
+	for _, alg := range []string{"HS256", "HS384", "HS512"} {
+		if _, err := verifyJWT(signJWT(t, alg, testJWTSecret, validClaims(now)), authConfig, now); err != nil {
+			t.Errorf("%s: %v", alg, err)
+		}
+	}
+
+	rejected := map[string]string{
+		"none":         signJWT(t, "none", "", validClaims(now)),
+		"RS256":        signJWT(t, "RS256", "", validClaims(now)),
+		"wrong secret": signJWT(t, "HS256", "other-secret", validClaims(now)),
+		"malformed":    "not-a-token",
+	}
+	for name, token := range rejected {
+		if _, err := verifyJWT(token, authConfig, now); !errors.Is(err, errInvalidToken) {
+			t.Errorf("%s: err = %v, want errInvalidToken", name, err)
+		}
+	}
+}
+
+func TestVerifyJWTClaims(t *testing.T) {
+	now := time.Now()
+	authConfig := AuthConfig{JWTSecret: testJWTSecret, JWTIssuer: "issuer", JWTAudience: "inventory"}
+
+	tests := []struct {
+		name   string
+		change func(claims map[string]any)
+		valid  bool
+	}{
+		{"valid", func(claims map[string]any) {}, true},
+		{"audience in a list", func(claims map[string]any) { claims["aud"] = []string{"other", "inventory"} }, true},
+		{"expired within leeway", func(claims map[string]any) { claims["exp"] = now.Add(-30 * time.Second).Unix() }, true},
+		{"expired", func(claims map[string]any) { claims["exp"] = now.Add(-2 * time.Minute).Unix() }, false},
+		{"no exp", func(claims map[string]any) { delete(claims, "exp") }, false},
+		{"not valid yet within leeway", func(claims map[string]any) { claims["nbf"] = now.Add(30 * time.Second).Unix() }, true},
+		{"not valid yet", func(claims map[string]any) { claims["nbf"] = now.Add(2 * time.Minute).Unix() }, false},
+		{"wrong issuer", func(claims map[string]any) { claims["iss"] = "someone-else" }, false},
+		{"wrong audience", func(claims map[string]any) { claims["aud"] = "other" }, false},
+		{"no subject", func(claims map[string]any) { delete(claims, "sub") }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims(now)
+			claims["iss"], claims["aud"] = "issuer", "inventory"
+			tt.change(claims)
+
+			_, err := verifyJWT(signJWT(t, "HS256", testJWTSecret, claims), authConfig, now)
+			if tt.valid && err != nil {
+				t.Errorf("err = %v, want the token accepted", err)
+			}
+			if !tt.valid && !errors.Is(err, errInvalidToken) {
+				t.Errorf("err = %v, want errInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestJWTRoleClaims(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims jwtClaims
+		want   Role
+	}{
+		{"none", jwtClaims{}, RoleViewer},
+		{"role", jwtClaims{Role: "stock-editor"}, RoleStockEditor},
+		{"highest of roles", jwtClaims{Roles: []string{"viewer", "catalog-admin", "stock-editor"}}, RoleCatalogAdmin},
+		{"role and roles", jwtClaims{Role: "admin", Roles: []string{"viewer"}}, RoleAdmin},
+		{"unknown", jwtClaims{Role: "owner"}, RoleViewer},
+	}
+
+	for _, tt := range tests {
+		if got := tt.claims.role(); got != tt.want {
+			t.Errorf("%s: role = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRequireAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	engine.Use(RequireAuth(AuthConfig{
+		APIKeys:   map[string]APIKey{"good-key": {Name: "ops", Role: RoleStockEditor}},
+		JWTSecret: testJWTSecret,
+	}))
+	engine.GET("/", func(ctx *gin.Context) {
+		caller, _ := CallerFromContext(ctx)
+		ctx.String(http.StatusOK, caller.ID+" "+caller.Role.String())
+	})
+
+	token := signJWT(t, "HS256", testJWTSecret, map[string]any{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix(), "role": "admin"})
+
+	tests := []struct {
+		name     string
+		header   string
+		value    string
+		wantCode int
+		wantBody string
+	}{
+		{"api key header", apiKeyHeader, "good-key", http.StatusOK, "ops stock-editor"},
+		{"api key as bearer", "Authorization", "Bearer good-key", http.StatusOK, "ops stock-editor"},
+		{"jwt", "Authorization", "Bearer " + token, http.StatusOK, "alice admin"},
+		{"unknown api key", apiKeyHeader, "bad-key", http.StatusUnauthorized, ""},
+		{"jwt in api key header", apiKeyHeader, token, http.StatusUnauthorized, ""},
+		{"nothing", "", "", http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tt.wantBody)
+			}
+			if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without a WWW-Authenticate header")
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"aoa-inventory/squareUtils/client"
+	"aoa-inventory/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/square/square-go-sdk/core"
+)
+
+const (
+	// rateLimitSweepInterval is how often buckets of clients that went quiet are dropped.
+	rateLimitSweepInterval = 10 * time.Minute
+
+	// squareRetryAfter is suggested to clients when Square itself kept rejecting calls.
+	squareRetryAfter = 5 * time.Second
+)
+
+// clientLimiter keeps a token bucket per client.
+type clientLimiter struct {
+	rate  float64
+	burst int
+
+	mu        sync.Mutex
+	buckets   map[string]*utils.TokenBucket
+	lastSweep time.Time
+}
+
+// RateLimit allows each client rate requests per second on average, with bursts of up to
+// burst requests, and answers the rest with 429 Too Many Requests and a Retry-After header.
+// It goes after RequireAuth, so clients are told apart by their authenticated caller ID, or by
+// address when authentication is disabled.
+func RateLimit(rate float64, burst int) gin.HandlerFunc {
+	limiter := newClientLimiter(rate, burst)
+
+	return func(ctx *gin.Context) {
+		key := "ip:" + ctx.ClientIP()
+		if caller, ok := CallerFromContext(ctx); ok {
+			key = "caller:" + caller.ID
+		}
+
+		if wait, ok := limiter.bucket(key).Reserve(0); !ok {
+			rejectRateLimited(ctx, wait)
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// RateLimitFailedAuth allows each address rate failed authentication attempts per second on
+// average, with bursts of up to burst, so keys, tokens and webhook signatures cannot be guessed
+// faster than that. Any 401 Unauthorized response counts as a failure. It goes before
+// RequireAuth and the webhook routes and turns an address away once its attempts are used up,
+// before its credentials are checked.
+func RateLimitFailedAuth(rate float64, burst int) gin.HandlerFunc {
+	limiter := newClientLimiter(rate, burst)
+
+	return func(ctx *gin.Context) {
+		bucket := limiter.bucket("ip:" + ctx.ClientIP())
+		if wait := bucket.Delay(); wait > 0 {
+			rejectRateLimited(ctx, wait)
+			return
+		}
+
+		ctx.Next()
+
+		if ctx.Writer.Status() == http.StatusUnauthorized {
+			bucket.Take()
+		}
+	}
+}
+
+func newClientLimiter(rate float64, burst int) *clientLimiter {
+	return &clientLimiter{rate: rate, burst: burst, buckets: map[string]*utils.TokenBucket{}, lastSweep: time.Now()}
+}
+
+func rejectRateLimited(ctx *gin.Context, wait time.Duration) {
+	log.Printf("ERROR: Rate limited %s %s from %s", ctx.Request.Method, ctx.Request.URL.Path, ctx.ClientIP())
+	setRetryAfter(ctx, wait)
+	ctx.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "too many requests"})
+}
+
+func (l *clientLimiter) bucket(key string) *utils.TokenBucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// A full bucket behaves like a new one, so dropping it only saves memory.
+	if time.Since(l.lastSweep) >= rateLimitSweepInterval {
+		for bucketKey, bucket := range l.buckets {
+			if bucket.Full() {
+				delete(l.buckets, bucketKey)
+			}
+		}
+		l.lastSweep = time.Now()
+	}
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = utils.NewTokenBucket(l.rate, l.burst)
+		l.buckets[key] = bucket
+	}
+	return bucket
+}
+
+// squareRateLimited reports whether err means Square calls are being rate limited, either by
+// the outbound limiter or by Square after the client's retries, and when to try again.
+func squareRateLimited(err error) (time.Duration, bool) {
+	var limitErr *client.RateLimitError
+	if errors.As(err, &limitErr) {
+		return limitErr.RetryAfter, true
+	}
+
+	var apiErr *core.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests {
+		return squareRetryAfter, true
+	}
+
+	return 0, false
+}
+
+// setRetryAfter sets the Retry-After header in whole seconds, rounding up.
+func setRetryAfter(ctx *gin.Context, wait time.Duration) {
+	seconds := max(1, int(math.Ceil(wait.Seconds())))
+	ctx.Header("Retry-After", strconv.Itoa(seconds))
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newRateLimitedEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	engine.Use(RateLimitFailedAuth(0.001, 2))
+	engine.Use(RequireAuth(AuthConfig{APIKeys: map[string]APIKey{
+		"key-one": {Name: "one", Role: RoleViewer},
+		"key-two": {Name: "two", Role: RoleViewer},
+	}}))
+	engine.Use(RateLimit(0.001, 2))
+	engine.GET("/", func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+	return engine
+}
+
+func requestStatuses(engine *gin.Engine, remoteAddr string, keys ...string) []int {
+	statuses := []int{}
+	for _, key := range keys {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set(apiKeyHeader, key)
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		statuses = append(statuses, rec.Code)
+	}
+	return statuses
+}
+
+func assertStatuses(t *testing.T, got []int, want ...int) {
+	t.Helper()
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("statuses = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestRateLimitFailedAuthIgnoresRotatingCredentials(t *testing.T) {
+	engine := newRateLimitedEngine()
+
+	statuses := requestStatuses(engine, "192.0.2.1:1234", "bogus-1", "bogus-2", "bogus-3", "bogus-4")
+	assertStatuses(t, statuses, http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests, http.StatusTooManyRequests)
+
+	// Other addresses still get their own attempts.
+	statuses = requestStatuses(engine, "192.0.2.2:1234", "bogus-5", "key-one")
+	assertStatuses(t, statuses, http.StatusUnauthorized, http.StatusOK)
+}
+
+func TestRateLimitKeysOnCaller(t *testing.T) {
+	engine := newRateLimitedEngine()
+
+	statuses := requestStatuses(engine, "192.0.2.1:1234", "key-one", "key-one", "key-one")
+	assertStatuses(t, statuses, http.StatusOK, http.StatusOK, http.StatusTooManyRequests)
+
+	// Another caller from the same address has its own bucket.
+	statuses = requestStatuses(engine, "192.0.2.1:1234", "key-two", "key-two")
+	assertStatuses(t, statuses, http.StatusOK, http.StatusOK)
+
+	// The first caller stays limited from another address.
+	statuses = requestStatuses(engine, "192.0.2.9:1234", "key-one")
+	assertStatuses(t, statuses, http.StatusTooManyRequests)
+}
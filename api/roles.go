@@ -0,0 +1,88 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"aoa-inventory/squareUtils"
+	"aoa-inventory/squareUtils/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Role is what a caller may do. Each role includes the permissions of the ones before it.
+type Role int
+
+const (
+	// RoleViewer may only read.
+	RoleViewer Role = iota + 1
+	// RoleStockEditor may also change stock, but not catalog fields such as names and prices.
+	RoleStockEditor
+	// RoleCatalogAdmin may also create, edit, archive and delete items.
+	RoleCatalogAdmin
+	// RoleAdmin may also read the audit log.
+	RoleAdmin
+)
+
+var roleNames = map[Role]string{
+	RoleViewer:       "viewer",
+	RoleStockEditor:  "stock-editor",
+	RoleCatalogAdmin: "catalog-admin",
+	RoleAdmin:        "admin",
+}
+
+func (r Role) String() string {
+	return roleNames[r]
+}
+
+// ParseRole returns the role with the given name.
+func ParseRole(name string) (Role, error) {
+	for role, roleName := range roleNames {
+		if roleName == name {
+			return role, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown role %q", name)
+}
+
+// requireRole rejects callers below the role with 403 Forbidden. Requests without a caller are
+// let through, since that only happens when authentication is disabled.
+func requireRole(role Role) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if caller, ok := CallerFromContext(ctx); ok && caller.Role < role {
+			log.Printf("ERROR: %s (%s) may not %s %s", caller.ID, caller.Role, ctx.Request.Method, ctx.FullPath())
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("requires the %s role", role)})
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// checkUpdateFields responds with 403 Forbidden, naming the field, when a caller below
+// RoleCatalogAdmin sends an update that would change a catalog field. It reports whether the
+// update may go ahead.
+func checkUpdateFields(ctx *gin.Context, locationID, sku string, update *models.InventoryItemUpdate) bool {
+	caller, ok := CallerFromContext(ctx)
+	if !ok || caller.Role >= RoleCatalogAdmin || !squareUtils.HasCatalogFields(update) {
+		return true
+	}
+
+	// The backend compares the update with the catalog the way it would when applying it, so a
+	// location price equal to the base price still counts as a new override.
+	fields, err := inventoryBackend.CatalogChanges(ctx.Request.Context(), locationID, sku, update)
+	if err != nil {
+		respondWithBackendError(ctx, err, "could not load inventory item")
+		return false
+	}
+
+	if len(fields) == 0 {
+		return true
+	}
+
+	log.Printf("ERROR: %s (%s) may not change %s of sku %s", caller.ID, caller.Role, fields[0], sku)
+	ctx.JSON(http.StatusForbidden, gin.H{
+		"error": fmt.Sprintf("changing %s requires the %s role", fields[0], RoleCatalogAdmin),
+		"field": fields[0],
+	})
+	return false
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"aoa-inventory/squareUtils"
+	"aoa-inventory/squareUtils/client"
+	"aoa-inventory/squareUtils/fakeSquare"
+	"aoa-inventory/squareUtils/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// useFakeBackend serves the API from a Square backend reading a fake Square server with a
+// single item, LAT-001, and returns the server and the backend's catalog cache. Tests using it
+// must not run in parallel, since the client is shared.
+func useFakeBackend(t *testing.T) (*fakeSquare.Server, *squareUtils.CatalogCache) {
+	t.Helper()
+
+	server := fakeSquare.NewServer([]models.InventoryItem{
+		{ID: "VAR_LATTE", Name: "Vanilla Latte", SKU: "LAT-001", CurrentStock: 10, Category: "Drink"},
+	}, "")
+	client.SquareClient = nil
+	client.Init("test-token", "fake", server.URL, 1000, 1000)
+
+	catalogCache := squareUtils.NewCatalogCache(time.Minute)
+	previous := inventoryBackend
+	inventoryBackend = squareUtils.NewSquareBackend(server.LocationID, catalogCache)
+
+	t.Cleanup(func() {
+		inventoryBackend = previous
+		server.Close()
+		client.SquareClient = nil
+	})
+
+	return server, catalogCache
+}
+
+func newCallerContext(role Role) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+	ctx.Request = httptest.NewRequest(http.MethodPut, "/", nil)
+	ctx.Set(callerKey, Caller{ID: "test", Role: role})
+	return ctx, rec
+}
+
+func TestCheckUpdateFields(t *testing.T) {
+	useFakeBackend(t)
+
+	amount := int64(450)
+	setPrice := &models.InventoryItemUpdate{Price: &models.PriceUpdate{Amount: &amount}}
+	if _, err := inventoryBackend.UpdateInventoryItem(t.Context(), "", "LAT-001", setPrice); err != nil {
+		t.Fatalf("UpdateInventoryItem: %v", err)
+	}
+
+	stock := 3
+	tests := []struct {
+		name    string
+		update  models.InventoryItemUpdate
+		allowed bool
+	}{
+		{"stock only", models.InventoryItemUpdate{CurrentStock: &stock}, true},
+		{"unchanged base price", models.InventoryItemUpdate{Price: &models.PriceUpdate{Amount: &amount}}, true},
+		// The variation has no override, so this would create one even though the price read
+		// at the location is the same.
+		{"location price equal to the base price", models.InventoryItemUpdate{Price: &models.PriceUpdate{Amount: &amount, AtLocation: true}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, rec := newCallerContext(RoleStockEditor)
+			if got := checkUpdateFields(ctx, "", "LAT-001", &tt.update); got != tt.allowed {
+				t.Fatalf("checkUpdateFields = %t, want %t (status %d)", got, tt.allowed, rec.Code)
+			}
+			if !tt.allowed && rec.Code != http.StatusForbidden {
+				t.Errorf("status = %d, want 403", rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"aoa-inventory/squareUtils"
+	"aoa-inventory/squareUtils/client"
+
+	"github.com/gin-gonic/gin"
+	square "github.com/square/square-go-sdk"
+)
+
+const (
+	squareSignatureHeader = "x-square-hmacsha256-signature"
+	maxWebhookBodyBytes   = 1 << 20
+
+	// Square retries failed deliveries for up to 72 hours, so remember event IDs at least that long.
+	webhookEventRetention = 72 * time.Hour
+
+	// webhookEventSweepInterval is how often expired event IDs are dropped.
+	webhookEventSweepInterval = time.Hour
+)
+
+// squareWebhookEvent is the subset of Square's webhook envelope this service reads.
+type squareWebhookEvent struct {
+	MerchantID string `json:"merchant_id"`
+	Type       string `json:"type"`
+	EventID    string `json:"event_id"`
+	CreatedAt  string `json:"created_at"`
+	Data       struct {
+		Type   string `json:"type"`
+		ID     string `json:"id"`
+		Object struct {
+			InventoryCounts []*square.InventoryCount `json:"inventory_counts"`
+		} `json:"object"`
+	} `json:"data"`
+}
+
+var (
+	webhookCatalogCache    *squareUtils.CatalogCache
+	webhookSignatureKey    string
+	webhookNotificationURL string
+	seenWebhookEvents      = newEventDeduper(webhookEventRetention)
+)
+
+// SetupWebhooks registers the Square webhook receiver. signatureKey and notificationURL must
+// match the webhook subscription in the Square dashboard for signatures to verify.
+func SetupWebhooks(webhookGroup *gin.RouterGroup, catalogCache *squareUtils.CatalogCache, signatureKey, notificationURL string) {
+	webhookCatalogCache = catalogCache
+	webhookSignatureKey = signatureKey
+	webhookNotificationURL = notificationURL
+
+	webhookGroup.POST("/square", HandleSquareWebhook)
+}
+
+func HandleSquareWebhook(ctx *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(ctx.Request.Body, maxWebhookBodyBytes))
+	if err != nil || len(body) == 0 {
+		log.Println("ERROR: Could not read webhook body")
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	if client.SquareClient == nil {
+		log.Println("ERROR: Square client is not initialized")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not verify webhook"})
+		return
+	}
+
+	verifyReq := &square.VerifySignatureRequest{
+		RequestBody:     string(body),
+		SignatureHeader: ctx.GetHeader(squareSignatureHeader),
+		SignatureKey:    webhookSignatureKey,
+		NotificationURL: webhookNotificationURL,
+	}
+	if err := client.SquareClient.Webhooks.VerifySignature(ctx.Request.Context(), verifyReq); err != nil {
+		log.Printf("ERROR: Rejected webhook with invalid signature: %v", err)
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid signature"})
+		return
+	}
+
+	var event squareWebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil || event.EventID == "" {
+		log.Printf("ERROR: Could not parse webhook event: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid event"})
+		return
+	}
+
+	// Square redelivers events it thinks failed; acknowledge duplicates without reprocessing.
+	if !seenWebhookEvents.markSeen(event.EventID) {
+		log.Printf("Ignoring duplicate webhook event %s", event.EventID)
+		ctx.JSON(http.StatusOK, gin.H{"status": "duplicate"})
+		return
+	}
+
+	switch event.Type {
+	case "inventory.count.updated":
+		variationIDs := []string{}
+		for _, count := range event.Data.Object.InventoryCounts {
+			if count != nil && count.CatalogObjectID != nil {
+				variationIDs = append(variationIDs, *count.CatalogObjectID)
+			}
+		}
+
+		log.Printf("Received inventory update for %d catalog objects (event %s)", len(variationIDs), event.EventID)
+		webhookCatalogCache.InvalidateIfUnknown(variationIDs...)
+	case "catalog.version.updated":
+		log.Printf("Received catalog version update (event %s)", event.EventID)
+		webhookCatalogCache.Invalidate()
+	default:
+		log.Printf("Ignoring unsupported webhook event type %q (event %s)", event.Type, event.EventID)
+		ctx.JSON(http.StatusOK, gin.H{"status": "ignored"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// eventDeduper remembers recently processed webhook event IDs.
+type eventDeduper struct {
+	retention time.Duration
+
+	mu        sync.Mutex
+	seenAt    map[string]time.Time
+	lastSweep time.Time
+}
+
+func newEventDeduper(retention time.Duration) *eventDeduper {
+	return &eventDeduper{retention: retention, seenAt: map[string]time.Time{}, lastSweep: time.Now()}
+}
+
+// markSeen records the event ID and reports whether it was new.
+func (d *eventDeduper) markSeen(eventID string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Expired IDs are dropped in one pass now and then rather than on every event. Until then
+	// they are only ignored.
+	now := time.Now()
+	if now.Sub(d.lastSweep) >= webhookEventSweepInterval {
+		for id, seenAt := range d.seenAt {
+			if now.Sub(seenAt) > d.retention {
+				delete(d.seenAt, id)
+			}
+		}
+		d.lastSweep = now
+	}
+
+	if seenAt, ok := d.seenAt[eventID]; ok && now.Sub(seenAt) <= d.retention {
+		return false
+	}
+
+	d.seenAt[eventID] = now
+	return true
+}
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"aoa-inventory/squareUtils"
+	"aoa-inventory/squareUtils/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	testWebhookKey = "webhook-key"
+	testWebhookURL = "https://inventory.example.com/webhooks/square"
+)
+
+// webhookTest serves the webhook receiver for the catalog cache of a fake backend. other
+// writes to the same fake Square with a cache of its own, as edits made elsewhere would.
+type webhookTest struct {
+	engine *gin.Engine
+	other  squareUtils.InventoryBackend
+}
+
+func newWebhookTest(t *testing.T) *webhookTest {
+	t.Helper()
+
+	server, catalogCache := useFakeBackend(t)
+
+	previous := seenWebhookEvents
+	seenWebhookEvents = newEventDeduper(webhookEventRetention)
+	t.Cleanup(func() { seenWebhookEvents = previous })
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	SetupWebhooks(engine.Group("/webhooks"), catalogCache, testWebhookKey, testWebhookURL)
+
+	return &webhookTest{
+		engine: engine,
+		other:  squareUtils.NewSquareBackend(server.LocationID, squareUtils.NewCatalogCache(time.Minute)),
+	}
+}
+
+// post delivers the event, signed the way Square signs it unless signature is given, and
+// returns the response status and its status field.
+func (w *webhookTest) post(t *testing.T, event map[string]any, signature string) (int, string) {
+	t.Helper()
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signature == "" {
+		mac := hmac.New(sha256.New, []byte(testWebhookKey))
+		mac.Write([]byte(testWebhookURL))
+		mac.Write(body)
+		signature = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/square", strings.NewReader(string(body)))
+	req.Header.Set(squareSignatureHeader, signature)
+	rec := httptest.NewRecorder()
+	w.engine.ServeHTTP(rec, req)
+
+	var response struct {
+		Status string `json:"status"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &response)
+	return rec.Code, response.Status
+}
+
+// renameElsewhere loads the catalog into the cache, then renames the latte behind its back.
+func (w *webhookTest) renameElsewhere(t *testing.T, name string) {
+	t.Helper()
+
+	listedName(t)
+	if _, err := w.other.UpdateInventoryItem(t.Context(), "", "LAT-001", &models.InventoryItemUpdate{Name: &name}); err != nil {
+		t.Fatalf("UpdateInventoryItem: %v", err)
+	}
+}
+
+// listedName returns the latte's name as listed from the cached catalog.
+func listedName(t *testing.T) string {
+	t.Helper()
+
+	inventory, err := inventoryBackend.ListInventory(t.Context(), squareUtils.InventoryQuery{})
+	if err != nil {
+		t.Fatalf("ListInventory: %v", err)
+	}
+	return inventory[0].Name
+}
+
+func inventoryCountEvent(eventID, variationID string) map[string]any {
+	return map[string]any{
+		"type":     "inventory.count.updated",
+		"event_id": eventID,
+		"data": map[string]any{
+			"object": map[string]any{
+				"inventory_counts": []map[string]any{{"catalog_object_id": variationID}},
+			},
+		},
+	}
+}
+
+func TestSquareWebhookRejectsBadSignature(t *testing.T) {
+	w := newWebhookTest(t)
+	w.renameElsewhere(t, "Iced Latte")
+
+	event := map[string]any{"type": "catalog.version.updated", "event_id": "evt-1"}
+	if code, _ := w.post(t, event, "bm90IGEgc2lnbmF0dXJl"); code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", code)
+	}
+	if got := listedName(t); got != "Vanilla Latte" {
+		t.Errorf("name = %q, want the cached Vanilla Latte", got)
+	}
+
+	// The rejected delivery must not use up the event ID.
+	if code, status := w.post(t, event, ""); code != http.StatusOK || status != "ok" {
+		t.Errorf("signed retry = %d %q, want 200 ok", code, status)
+	}
+}
+
+func TestSquareWebhookInvalidatesCache(t *testing.T) {
+	tests := []struct {
+		name        string
+		event       map[string]any
+		status      string
+		invalidates bool
+	}{
+		{"catalog update", map[string]any{"type": "catalog.version.updated", "event_id": "evt-1"}, "ok", true},
+		{"count of a known variation", inventoryCountEvent("evt-1", "VAR_LATTE"), "ok", false},
+		{"count of an unknown variation", inventoryCountEvent("evt-1", "VAR_NEW"), "ok", true},
+		{"unsupported type", map[string]any{"type": "order.created", "event_id": "evt-1"}, "ignored", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newWebhookTest(t)
+			w.renameElsewhere(t, "Iced Latte")
+
+			if code, status := w.post(t, tt.event, ""); code != http.StatusOK || status != tt.status {
+				t.Fatalf("response = %d %q, want 200 %q", code, status, tt.status)
+			}
+
+			want := "Vanilla Latte"
+			if tt.invalidates {
+				want = "Iced Latte"
+			}
+			if got := listedName(t); got != want {
+				t.Errorf("name = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestSquareWebhookIgnoresRepeatedEvent(t *testing.T) {
+	w := newWebhookTest(t)
+	event := map[string]any{"type": "catalog.version.updated", "event_id": "evt-1"}
+
+	if code, status := w.post(t, event, ""); code != http.StatusOK || status != "ok" {
+		t.Fatalf("first delivery = %d %q, want 200 ok", code, status)
+	}
+
+	w.renameElsewhere(t, "Iced Latte")
+	if code, status := w.post(t, event, ""); code != http.StatusOK || status != "duplicate" {
+		t.Fatalf("redelivery = %d %q, want 200 duplicate", code, status)
+	}
+	if got := listedName(t); got != "Vanilla Latte" {
+		t.Errorf("name = %q, want the cached Vanilla Latte", got)
+	}
+}
+
+func TestEventDeduperForgetsExpiredEvents(t *testing.T) {
+	deduper := newEventDeduper(10 * time.Millisecond)
+
+	if !deduper.markSeen("evt-1") {
+		t.Fatal("first event was not new")
+	}
+	if deduper.markSeen("evt-1") {
+		t.Fatal("repeated event was new")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if !deduper.markSeen("evt-1") {
+		t.Error("event past the retention was not new")
+	}
+}
@@ -0,0 +1,215 @@
+package audit
+
+import (
+	"aoa-inventory/squareUtils/models"
+	"aoa-inventory/utils"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var log = utils.NewLogger("AUDIT")
+
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionAdjust = "adjust"
+	ActionBatch  = "batch"
+	ActionDelete = "delete"
+	ActionImage  = "image"
+
+	ResultOK    = "ok"
+	ResultError = "error"
+)
+
+// maxRecordSize bounds the length of one line read back from the log.
+const maxRecordSize = 1 << 20
+
+// Log appends audit records to a JSONL file. Once the file would grow past maxSize it is rotated
+// to path.1, shifting older files up to path.<maxFiles> and dropping the oldest.
+type Log struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// Filter selects records in a Query. Empty fields match everything.
+type Filter struct {
+	SKU   string
+	User  string
+	Since time.Time
+	Until time.Time
+	Limit int
+}
+
+// Open opens the log at path for appending, creating it if needed. maxFiles must be at least 1.
+func Open(path string, maxSize int64, maxFiles int) (*Log, error) {
+	l := &Log{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Log) open() error {
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	l.file, l.size = file, info.Size()
+	return nil
+}
+
+// Append writes the record as one line, rotating the file first if it is full.
+func (l *Log) Append(record *models.AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	return err
+}
+
+func (l *Log) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+
+	// Renaming over path.<maxFiles> drops the oldest file.
+	for i := l.maxFiles; i > 0; i-- {
+		if err := os.Rename(l.rotatedPath(i-1), l.rotatedPath(i)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	log.Printf("Rotated audit log %s", l.path)
+
+	return l.open()
+}
+
+// rotatedPath returns the path of the nth rotated file, where 0 is the live file.
+func (l *Log) rotatedPath(n int) string {
+	if n == 0 {
+		return l.path
+	}
+	return l.path + "." + strconv.Itoa(n)
+}
+
+// Query returns the records matching the filter, newest first and at most filter.Limit of them
+// when it is set. Rotated files are searched too.
+func (l *Log) Query(filter Filter) ([]models.AuditRecord, error) {
+	files, err := l.openAll()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for _, f := range files {
+			f.file.Close()
+		}
+	}()
+
+	records := []models.AuditRecord{}
+	for _, f := range files {
+		scanner := bufio.NewScanner(io.LimitReader(f.file, f.size))
+		scanner.Buffer(nil, maxRecordSize)
+		for scanner.Scan() {
+			var record models.AuditRecord
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				log.Printf("ERROR: Skipping unreadable line in %s: %v", f.file.Name(), err)
+				continue
+			}
+			if filter.matches(record) {
+				records = append(records, record)
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	slices.Reverse(records)
+	if filter.Limit > 0 && len(records) > filter.Limit {
+		records = records[:filter.Limit]
+	}
+	return records, nil
+}
+
+// auditFile is one log file opened for reading and its size when it was opened.
+type auditFile struct {
+	file *os.File
+	size int64
+}
+
+// openAll opens every log file, oldest first. Only opening them holds the lock: the open files
+// survive a rotation, and reading the live file stops at its size when it was opened, so
+// appends are not held up while a query reads.
+func (l *Log) openAll() ([]auditFile, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	files := []auditFile{}
+	for n := l.maxFiles; n >= 0; n-- {
+		file, err := os.Open(l.rotatedPath(n))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			for _, opened := range files {
+				opened.file.Close()
+			}
+			return nil, err
+		}
+
+		size := int64(math.MaxInt64)
+		if n == 0 {
+			size = l.size
+		}
+		files = append(files, auditFile{file: file, size: size})
+	}
+	return files, nil
+}
+
+func (f Filter) matches(record models.AuditRecord) bool {
+	return (f.SKU == "" || record.SKU == f.SKU) &&
+		(f.User == "" || record.User == f.User) &&
+		(f.Since.IsZero() || !record.Time.Before(f.Since)) &&
+		(f.Until.IsZero() || record.Time.Before(f.Until))
+}
+
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.file.Close()
+}
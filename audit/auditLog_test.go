@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"aoa-inventory/squareUtils/models"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func openTestLog(t *testing.T, maxSize int64, maxFiles int) *Log {
+	t.Helper()
+
+	l, err := Open(filepath.Join(t.TempDir(), "audit.jsonl"), maxSize, maxFiles)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+func appendRecord(t *testing.T, l *Log, sku string, at time.Time) {
+	t.Helper()
+
+	if err := l.Append(&models.AuditRecord{Time: at, User: "ops", Action: ActionUpdate, SKU: sku, Result: ResultOK}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+}
+
+func TestQueryReadsRotatedFilesNewestFirst(t *testing.T) {
+	l := openTestLog(t, 300, 10)
+	start := time.Now().UTC()
+	for i := range 20 {
+		appendRecord(t, l, "SKU-"+strconv.Itoa(i%2), start.Add(time.Duration(i)*time.Second))
+	}
+
+	records, err := l.Query(Filter{})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(records) != 20 {
+		t.Fatalf("got %d records, want 20", len(records))
+	}
+	for i := 1; i < len(records); i++ {
+		if records[i].Time.After(records[i-1].Time) {
+			t.Fatalf("record %d is newer than record %d", i, i-1)
+		}
+	}
+
+	records, err = l.Query(Filter{SKU: "SKU-1", Since: start.Add(10 * time.Second), Limit: 3})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(records) != 3 || records[0].Time != start.Add(19*time.Second) {
+		t.Errorf("filtered records = %v, want the 3 newest of SKU-1", records)
+	}
+}
+
+func TestQueryDropsOldestFiles(t *testing.T) {
+	l := openTestLog(t, 300, 1)
+	for range 20 {
+		appendRecord(t, l, "SKU", time.Now().UTC())
+	}
+
+	records, err := l.Query(Filter{})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(records) == 0 || len(records) >= 20 {
+		t.Errorf("got %d records, want only those in the live file and one rotated file", len(records))
+	}
+}
+
+func TestQueryWhileAppending(t *testing.T) {
+	l := openTestLog(t, 2000, 3)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range 200 {
+			if err := l.Append(&models.AuditRecord{Time: time.Now().UTC(), SKU: "SKU"}); err != nil {
+				t.Errorf("Append: %v", err)
+				return
+			}
+		}
+	}()
+
+	for range 20 {
+		if _, err := l.Query(Filter{}); err != nil {
+			t.Errorf("Query: %v", err)
+		}
+	}
+	wg.Wait()
+
+	records, err := l.Query(Filter{})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(records) == 0 {
+		t.Error("no records after appending")
+	}
+}
@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"aoa-inventory/squareUtils/models"
+	"context"
+	"slices"
+	"sync"
+)
+
+// Trail collects the audit records of one request while the backend works on it. The backend
+// fills in what only it knows, such as variation IDs, stock before and after, and Square
+// idempotency keys; the handler adds the caller and the result and appends the records to the log.
+// Records are only changed under the trail's lock, so goroutines may share a trail.
+type Trail struct {
+	mu      sync.Mutex
+	records []*models.AuditRecord
+}
+
+type trailKey struct{}
+
+// WithTrail returns a context carrying a new, empty trail.
+func WithTrail(ctx context.Context) (context.Context, *Trail) {
+	trail := &Trail{}
+	return context.WithValue(ctx, trailKey{}, trail), trail
+}
+
+// Update calls fill with the trail's record for the SKU, adding one if needed, and returns a
+// copy of the record as filled in.
+func (t *Trail) Update(sku string, fill func(record *models.AuditRecord)) models.AuditRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	i := slices.IndexFunc(t.records, func(record *models.AuditRecord) bool { return record.SKU == sku })
+	if i < 0 {
+		i = len(t.records)
+		t.records = append(t.records, &models.AuditRecord{SKU: sku})
+	}
+
+	fill(t.records[i])
+	return *t.records[i]
+}
+
+// Records returns a copy of every record on the trail, in the order they were added.
+func (t *Trail) Records() []models.AuditRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	records := make([]models.AuditRecord, len(t.records))
+	for i, record := range t.records {
+		records[i] = *record
+	}
+	return records
+}
+
+// Note calls fill with the record for the SKU on the context's trail. Without a trail nothing is
+// recorded, so callers never need to check.
+func Note(ctx context.Context, sku string, fill func(record *models.AuditRecord)) {
+	if trail, ok := ctx.Value(trailKey{}).(*Trail); ok {
+		trail.Update(sku, fill)
+	}
+}
+
+// NoteIdempotencyKey sets the idempotency key of a Square request on the records of the
+// variations it changed.
+func NoteIdempotencyKey(ctx context.Context, key string, variationIDs ...string) {
+	trail, ok := ctx.Value(trailKey{}).(*Trail)
+	if !ok {
+		return
+	}
+
+	trail.mu.Lock()
+	defer trail.mu.Unlock()
+
+	for _, record := range trail.records {
+		if slices.Contains(variationIDs, record.VariationID) {
+			record.IdempotencyKey = key
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package audit
+
+import (
+	"aoa-inventory/squareUtils/models"
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestTrailConcurrentNotes(t *testing.T) {
+	ctx, trail := WithTrail(context.Background())
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			sku := fmt.Sprintf("SKU-%d", i)
+			variationID := fmt.Sprintf("VAR_%d", i)
+			Note(ctx, sku, func(record *models.AuditRecord) { record.VariationID = variationID })
+			NoteIdempotencyKey(ctx, "key-"+sku, variationID)
+			trail.Records()
+		}()
+	}
+	wg.Wait()
+
+	records := trail.Records()
+	if len(records) != 20 {
+		t.Fatalf("got %d records, want 20", len(records))
+	}
+	for _, record := range records {
+		if record.IdempotencyKey != "key-"+record.SKU {
+			t.Errorf("%s: idempotency key = %q, want key-%s", record.SKU, record.IdempotencyKey, record.SKU)
+		}
+	}
+}
@@ -4,17 +4,63 @@ import (
 	"aoa-inventory/utils"
 	"github.com/joho/godotenv"
 	"os"
+	"strconv"
 	"strings"
+	"time"
+)
+
+const (
+	InventoryBackendSquare = "square"
+	InventoryBackendFile   = "file"
+
+	// SquareEnvFake starts an in-process fake Square server seeded from SampleInventoryPath.
+	SquareEnvFake = "fake"
 )
 
 var (
-	Port              string
-	AllowedOrigins    []string
-	SquareAccessToken string
-	SquareEnv         string
-	SquareLocationID  string
+	Port                string
+	AllowedOrigins      []string
+	InventoryBackend    string
+	SampleInventoryPath string
+	SquareAccessToken   string
+	SquareEnv           string
+	SquareBaseURL       string
+	SquareLocationID    string
+	CatalogCacheTTL     time.Duration
+
+	SquareWebhookSignatureKey string
+	SquareWebhookURL          string
+
+	AuditLogPath     string
+	AuditLogMaxSize  int64
+	AuditLogMaxFiles int
+
+	// APIKeys maps each static API key to the caller using it.
+	APIKeys     map[string]APIKey
+	JWTSecret   string
+	JWTIssuer   string
+	JWTAudience string
+	AuthEnabled bool
+
+	// RateLimit and RateLimitBurst limit requests per client; SquareRateLimit and
+	// SquareRateLimitBurst limit calls to Square across all clients. Rates are per second.
+	RateLimit            float64
+	RateLimitBurst       int
+	SquareRateLimit      float64
+	SquareRateLimitBurst int
+
+	// AuthFailureLimit and AuthFailureBurst limit failed authentication attempts per address.
+	// AuthFailureLimit is per minute, so guessing keys stays slow.
+	AuthFailureLimit float64
+	AuthFailureBurst int
 )
 
+// APIKey is the caller name and role a static API key stands for.
+type APIKey struct {
+	Name string
+	Role string
+}
+
 var log = utils.NewLogger("CONFIG")
 
 func Load() {
@@ -36,17 +82,157 @@ func Load() {
 		log.Fatalln("ERROR: Could not find 'ALLOWED_ORIGINS' in env file.")
 	}
 
-	SquareAccessToken = os.Getenv("SQUARE_ACCESS_TOKEN")
-	if SquareAccessToken == "" {
-		log.Fatalln("ERROR: Could not find 'SQUARE_ACCESS_TOKEN' in env file.")
+	InventoryBackend = strings.ToLower(os.Getenv("INVENTORY_BACKEND"))
+	switch InventoryBackend {
+	case "":
+		InventoryBackend = InventoryBackendSquare
+	case InventoryBackendSquare, InventoryBackendFile:
+	default:
+		log.Fatalf("ERROR: Invalid 'INVENTORY_BACKEND' %q, expected %q or %q.", InventoryBackend, InventoryBackendSquare, InventoryBackendFile)
+	}
+
+	SampleInventoryPath = os.Getenv("SAMPLE_INVENTORY_PATH")
+	if SampleInventoryPath == "" {
+		SampleInventoryPath = "data.json"
+	}
+
+	loadAuditLog()
+	loadAuth()
+
+	RateLimit = positiveFloatEnv("RATE_LIMIT", 5)
+	RateLimitBurst = positiveIntEnv("RATE_LIMIT_BURST", 20)
+	AuthFailureLimit = positiveFloatEnv("AUTH_FAILURE_LIMIT", 5)
+	AuthFailureBurst = positiveIntEnv("AUTH_FAILURE_BURST", 10)
+
+	// The file backend runs entirely offline, so Square settings are optional.
+	if InventoryBackend == InventoryBackendFile {
+		log.Printf("Using file inventory backend at %s", SampleInventoryPath)
+	} else {
+		loadSquare()
+	}
+}
+
+func loadAuditLog() {
+	AuditLogPath = os.Getenv("AUDIT_LOG_PATH")
+	if AuditLogPath == "" {
+		AuditLogPath = "audit.jsonl"
+	}
+
+	AuditLogMaxSize = int64(positiveIntEnv("AUDIT_LOG_MAX_SIZE_MB", 10)) << 20
+	AuditLogMaxFiles = positiveIntEnv("AUDIT_LOG_MAX_FILES", 5)
+}
+
+// positiveIntEnv reads a positive integer from the environment, or returns fallback when unset.
+func positiveIntEnv(name string, fallback int) int {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil || value <= 0 {
+		log.Fatalf("ERROR: Invalid '%s' %q, expected a positive whole number.", name, raw)
 	}
+	return value
+}
+
+// positiveFloatEnv reads a positive number from the environment, or returns fallback when unset.
+func positiveFloatEnv(name string, fallback float64) float64 {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback
+	}
+
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || value <= 0 {
+		log.Fatalf("ERROR: Invalid '%s' %q, expected a positive number.", name, raw)
+	}
+	return value
+}
+
+func loadAuth() {
+	AuthEnabled = true
+	if disabled, _ := strconv.ParseBool(os.Getenv("AUTH_DISABLED")); disabled {
+		AuthEnabled = false
+		log.Println("WARNING: Authentication is disabled, anyone who can reach the api can change stock")
+		return
+	}
+
+	// Keys without a role may only read.
+	APIKeys = map[string]APIKey{}
+	if rawKeys := os.Getenv("API_KEYS"); rawKeys != "" {
+		for _, entry := range strings.Split(rawKeys, ",") {
+			parts := strings.Split(strings.TrimSpace(entry), ":")
+			if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+				log.Fatalf("ERROR: Invalid 'API_KEYS' entry %q, expected name:key or name:key:role.", entry)
+			}
+			if _, ok := APIKeys[parts[1]]; ok {
+				log.Fatalf("ERROR: Duplicate key in 'API_KEYS' for %q.", parts[0])
+			}
+
+			apiKey := APIKey{Name: parts[0], Role: "viewer"}
+			if len(parts) == 3 {
+				apiKey.Role = parts[2]
+			}
+			APIKeys[parts[1]] = apiKey
+		}
+	}
+
+	JWTSecret = os.Getenv("JWT_SECRET")
+	JWTIssuer = os.Getenv("JWT_ISSUER")
+	JWTAudience = os.Getenv("JWT_AUDIENCE")
+
+	if len(APIKeys) == 0 && JWTSecret == "" {
+		log.Fatalln("ERROR: Could not find 'API_KEYS' or 'JWT_SECRET' in env file. Set 'AUTH_DISABLED=true' to run without authentication.")
+	}
+}
 
+func loadSquare() {
 	SquareEnv = os.Getenv("SQUARE_ENV")
 	if SquareEnv == "" {
 		log.Fatalln("ERROR: Could not find 'SQUARE_ENV' in env file.")
 	}
 
+	SquareBaseURL = os.Getenv("SQUARE_BASE_URL")
+
+	CatalogCacheTTL = 5 * time.Minute
+	if rawTTL := os.Getenv("CATALOG_CACHE_TTL"); rawTTL != "" {
+		ttl, err := time.ParseDuration(rawTTL)
+		if err != nil || ttl <= 0 {
+			log.Fatalf("ERROR: Invalid 'CATALOG_CACHE_TTL' %q, expected a positive duration like 5m.", rawTTL)
+		}
+		CatalogCacheTTL = ttl
+	}
+
+	// Square allows roughly 10 calls per second per application before answering 429.
+	SquareRateLimit = positiveFloatEnv("SQUARE_RATE_LIMIT", 8)
+	SquareRateLimitBurst = positiveIntEnv("SQUARE_RATE_LIMIT_BURST", 16)
+
+	SquareWebhookSignatureKey = os.Getenv("SQUARE_WEBHOOK_SIGNATURE_KEY")
+	SquareWebhookURL = os.Getenv("SQUARE_WEBHOOK_URL")
+	if SquareWebhookSignatureKey != "" && SquareWebhookURL == "" {
+		log.Fatalln("ERROR: 'SQUARE_WEBHOOK_URL' is required when 'SQUARE_WEBHOOK_SIGNATURE_KEY' is set.")
+	}
+
+	SquareAccessToken = os.Getenv("SQUARE_ACCESS_TOKEN")
 	SquareLocationID = os.Getenv("SQUARE_LOCATION_ID")
+
+	// The fake Square server accepts any token and seeds its own location.
+	if SquareEnv == SquareEnvFake {
+		if SquareAccessToken == "" {
+			SquareAccessToken = "fake-access-token"
+		}
+		if SquareLocationID == "" {
+			SquareLocationID = "FAKE_LOCATION"
+		}
+		log.Printf("Using fake Square server seeded from %s", SampleInventoryPath)
+		return
+	}
+
+	if SquareAccessToken == "" {
+		log.Fatalln("ERROR: Could not find 'SQUARE_ACCESS_TOKEN' in env file.")
+	}
+
 	if SquareLocationID == "" {
 		log.Fatalln("ERROR: Could not find 'SQUARE_LOCATION_ID' in env file.")
 	}
@@ -2,8 +2,11 @@ package main
 
 import (
 	"aoa-inventory/api"
+	"aoa-inventory/audit"
 	"aoa-inventory/config"
+	"aoa-inventory/squareUtils"
 	squareClient "aoa-inventory/squareUtils/client"
+	"aoa-inventory/squareUtils/fakeSquare"
 	"aoa-inventory/utils"
 	"net/http"
 	"time"
@@ -21,8 +24,36 @@ func main() {
 	// setup gin
 	gin.SetMode(gin.ReleaseMode)
 
-	// setup square client
-	squareClient.Init(config.SquareAccessToken, config.SquareEnv)
+	// setup inventory backend
+	var inventoryBackend squareUtils.InventoryBackend
+	var catalogCache *squareUtils.CatalogCache
+	switch config.InventoryBackend {
+	case config.InventoryBackendFile:
+		inventoryBackend = squareUtils.NewFileBackend(config.SampleInventoryPath)
+	default:
+		squareBaseURL := config.SquareBaseURL
+		if config.SquareEnv == config.SquareEnvFake {
+			fixture, err := fakeSquare.LoadFixture(config.SampleInventoryPath)
+			if err != nil {
+				log.Fatalln("ERROR: Could not load fake Square fixture:", err)
+			}
+
+			fakeServer := fakeSquare.NewServer(fixture, config.SquareLocationID)
+			defer fakeServer.Close()
+			squareBaseURL = fakeServer.URL
+		}
+
+		squareClient.Init(config.SquareAccessToken, config.SquareEnv, squareBaseURL, config.SquareRateLimit, config.SquareRateLimitBurst)
+		catalogCache = squareUtils.NewCatalogCache(config.CatalogCacheTTL)
+		inventoryBackend = squareUtils.NewSquareBackend(config.SquareLocationID, catalogCache)
+	}
+
+	// setup audit log of every write made through the api
+	auditLog, err := audit.Open(config.AuditLogPath, config.AuditLogMaxSize, config.AuditLogMaxFiles)
+	if err != nil {
+		log.Fatalln("ERROR: Could not open audit log:", err)
+	}
+	defer auditLog.Close()
 
 	// setup healthcheck route before setting cors
 	ginEngine := gin.Default()
@@ -30,17 +61,45 @@ func main() {
 		ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
 
+	// limit failed authentication on every route after the healthcheck, webhook signatures included
+	ginEngine.Use(api.RateLimitFailedAuth(config.AuthFailureLimit/60, config.AuthFailureBurst))
+
+	// setup square webhooks, which are server-to-server and also skip cors
+	if catalogCache != nil && config.SquareWebhookSignatureKey != "" {
+		webhookGroup := ginEngine.Group("/webhooks")
+		api.SetupWebhooks(webhookGroup, catalogCache, config.SquareWebhookSignatureKey, config.SquareWebhookURL)
+	}
+
 	ginEngine.Use(cors.New(cors.Config{
 		AllowOrigins:     config.AllowedOrigins,
-		AllowMethods:     []string{"GET", "PUT", "OPTIONS"},
-		AllowHeaders:     []string{"Content-Type", "Authorization"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Content-Type", "Authorization", "X-API-Key", "If-Match"},
+		ExposeHeaders:    []string{"ETag", "Retry-After", "X-Total-Count", "X-Next-Cursor"},
 		AllowCredentials: true,
 		MaxAge:           10 * time.Minute,
 	}))
 
 	// setup endpoints for the api
 	apiGroup := ginEngine.Group("/api")
-	api.SetupEndpoints(apiGroup)
+	if config.AuthEnabled {
+		apiKeys := map[string]api.APIKey{}
+		for key, apiKey := range config.APIKeys {
+			role, err := api.ParseRole(apiKey.Role)
+			if err != nil {
+				log.Fatalf("ERROR: Invalid role for API key %q: %v", apiKey.Name, err)
+			}
+			apiKeys[key] = api.APIKey{Name: apiKey.Name, Role: role}
+		}
+
+		apiGroup.Use(api.RequireAuth(api.AuthConfig{
+			APIKeys:     apiKeys,
+			JWTSecret:   config.JWTSecret,
+			JWTIssuer:   config.JWTIssuer,
+			JWTAudience: config.JWTAudience,
+		}))
+	}
+	apiGroup.Use(api.RateLimit(config.RateLimit, config.RateLimitBurst))
+	api.SetupEndpoints(apiGroup, inventoryBackend, auditLog)
 
 	// start server
 	log.Printf("Server started on port %s...\n", config.Port)
@@ -0,0 +1,140 @@
+package squareUtils
+
+import (
+	"aoa-inventory/squareUtils/models"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	square "github.com/square/square-go-sdk"
+)
+
+var ErrInvalidAdjustment = errors.New("invalid stock adjustment")
+
+// AdjustmentReason says why stock changed, which decides the Square inventory states the
+// quantity moves between so sales reports only count real sales.
+type AdjustmentReason string
+
+const (
+	ReasonReceived   AdjustmentReason = "received"
+	ReasonSold       AdjustmentReason = "sold"
+	ReasonWaste      AdjustmentReason = "waste"
+	ReasonDamaged    AdjustmentReason = "damaged"
+	ReasonTheft      AdjustmentReason = "theft"
+	ReasonReturn     AdjustmentReason = "return"
+	ReasonCorrection AdjustmentReason = "correction"
+)
+
+type stateTransition struct {
+	from square.InventoryState
+	to   square.InventoryState
+}
+
+// Square has no damaged or theft states; its dashboard records both as WASTE as well. Square
+// only accepts documented transitions, and stock cannot move from IN_STOCK back to NONE, so a
+// correction down is recorded as WASTE too.
+var (
+	increaseTransitions = map[AdjustmentReason]stateTransition{
+		ReasonReceived:   {square.InventoryStateNone, square.InventoryStateInStock},
+		ReasonReturn:     {square.InventoryStateUnlinkedReturn, square.InventoryStateInStock},
+		ReasonCorrection: {square.InventoryStateNone, square.InventoryStateInStock},
+	}
+	decreaseTransitions = map[AdjustmentReason]stateTransition{
+		ReasonSold:       {square.InventoryStateInStock, square.InventoryStateSold},
+		ReasonWaste:      {square.InventoryStateInStock, square.InventoryStateWaste},
+		ReasonDamaged:    {square.InventoryStateInStock, square.InventoryStateWaste},
+		ReasonTheft:      {square.InventoryStateInStock, square.InventoryStateWaste},
+		ReasonCorrection: {square.InventoryStateInStock, square.InventoryStateWaste},
+	}
+)
+
+// ParseAdjustmentReason parses a reason such as "waste", ignoring case. An empty string is
+// allowed and picks the default reason for the direction of the change.
+func ParseAdjustmentReason(raw string) (AdjustmentReason, error) {
+	reason := AdjustmentReason(strings.ToLower(strings.TrimSpace(raw)))
+	if reason == "" {
+		return "", nil
+	}
+
+	if _, ok := increaseTransitions[reason]; ok {
+		return reason, nil
+	}
+	if _, ok := decreaseTransitions[reason]; ok {
+		return reason, nil
+	}
+
+	return "", fmt.Errorf("%w: unknown reason %q", ErrInvalidAdjustment, raw)
+}
+
+// checkAdjustment rejects a zero delta, and reasons that cannot explain its direction.
+func (r AdjustmentReason) checkAdjustment(delta int) error {
+	if delta == 0 {
+		return fmt.Errorf("%w: delta must be non-zero", ErrInvalidAdjustment)
+	}
+	return r.checkDirection(delta)
+}
+
+// checkDirection rejects reasons that cannot explain a change in the direction of delta, such
+// as stock going up because it was sold.
+func (r AdjustmentReason) checkDirection(delta int) error {
+	if r == "" || delta == 0 {
+		return nil
+	}
+
+	transitions, direction := increaseTransitions, "increase"
+	if delta < 0 {
+		transitions, direction = decreaseTransitions, "decrease"
+	}
+
+	if _, ok := transitions[r]; !ok {
+		return fmt.Errorf("%w: %q cannot %s stock", ErrInvalidAdjustment, r, direction)
+	}
+
+	return nil
+}
+
+// transition returns the states a change of delta moves stock between. Without a reason,
+// increases are recorded as received and decreases as sold.
+func (r AdjustmentReason) transition(delta int) (stateTransition, error) {
+	if err := r.checkDirection(delta); err != nil {
+		return stateTransition{}, err
+	}
+
+	if delta < 0 {
+		if r == "" {
+			r = ReasonSold
+		}
+		return decreaseTransitions[r], nil
+	}
+
+	if r == "" {
+		r = ReasonReceived
+	}
+	return increaseTransitions[r], nil
+}
+
+// parseStockUpdate validates the stock fields of an inventory update and returns its reason and
+// when the stock was counted, which defaults to now.
+func parseStockUpdate(update *models.InventoryItemUpdate) (AdjustmentReason, time.Time, error) {
+	now := time.Now()
+
+	if update.CurrentStock != nil && *update.CurrentStock < 0 {
+		return "", now, fmt.Errorf("%w: currentStock cannot be negative", ErrInvalidAdjustment)
+	}
+
+	countedAt := now
+	if update.CountedAt != nil {
+		if update.CountedAt.After(now) {
+			return "", now, fmt.Errorf("%w: countedAt cannot be in the future", ErrInvalidAdjustment)
+		}
+		countedAt = *update.CountedAt
+	}
+
+	if update.Reason == nil {
+		return "", countedAt, nil
+	}
+
+	reason, err := ParseAdjustmentReason(*update.Reason)
+	return reason, countedAt, err
+}
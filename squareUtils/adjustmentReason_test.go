@@ -0,0 +1,107 @@
+package squareUtils
+
+import (
+	"aoa-inventory/squareUtils/models"
+	"errors"
+	"testing"
+	"time"
+
+	square "github.com/square/square-go-sdk"
+)
+
+func TestParseAdjustmentReason(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    AdjustmentReason
+		wantErr bool
+	}{
+		{"", "", false},
+		{"  ", "", false},
+		{"waste", ReasonWaste, false},
+		{" Received ", ReasonReceived, false},
+		{"THEFT", ReasonTheft, false},
+		{"lost", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAdjustmentReason(tt.raw)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseAdjustmentReason(%q) = %q, %v; want %q, error %t", tt.raw, got, err, tt.want, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidAdjustment) {
+			t.Errorf("ParseAdjustmentReason(%q) error = %v, want ErrInvalidAdjustment", tt.raw, err)
+		}
+	}
+}
+
+func TestAdjustmentReasonTransition(t *testing.T) {
+	tests := []struct {
+		reason AdjustmentReason
+		delta  int
+		from   square.InventoryState
+		to     square.InventoryState
+	}{
+		{"", 3, square.InventoryStateNone, square.InventoryStateInStock},
+		{"", -3, square.InventoryStateInStock, square.InventoryStateSold},
+		{ReasonReceived, 1, square.InventoryStateNone, square.InventoryStateInStock},
+		{ReasonReturn, 1, square.InventoryStateUnlinkedReturn, square.InventoryStateInStock},
+		{ReasonCorrection, 1, square.InventoryStateNone, square.InventoryStateInStock},
+		{ReasonCorrection, -1, square.InventoryStateInStock, square.InventoryStateWaste},
+		{ReasonSold, -1, square.InventoryStateInStock, square.InventoryStateSold},
+		{ReasonWaste, -1, square.InventoryStateInStock, square.InventoryStateWaste},
+		{ReasonDamaged, -1, square.InventoryStateInStock, square.InventoryStateWaste},
+		{ReasonTheft, -1, square.InventoryStateInStock, square.InventoryStateWaste},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.reason.transition(tt.delta)
+		if err != nil {
+			t.Errorf("%q.transition(%d): %v", tt.reason, tt.delta, err)
+			continue
+		}
+		if got.from != tt.from || got.to != tt.to {
+			t.Errorf("%q.transition(%d) = %s -> %s, want %s -> %s", tt.reason, tt.delta, got.from, got.to, tt.from, tt.to)
+		}
+	}
+}
+
+func TestAdjustmentReasonTransitionRejectsWrongDirection(t *testing.T) {
+	tests := []struct {
+		reason AdjustmentReason
+		delta  int
+	}{
+		{ReasonSold, 1},
+		{ReasonWaste, 2},
+		{ReasonDamaged, 1},
+		{ReasonTheft, 1},
+		{ReasonReceived, -1},
+		{ReasonReturn, -4},
+	}
+
+	for _, tt := range tests {
+		if _, err := tt.reason.transition(tt.delta); !errors.Is(err, ErrInvalidAdjustment) {
+			t.Errorf("%q.transition(%d) error = %v, want ErrInvalidAdjustment", tt.reason, tt.delta, err)
+		}
+	}
+}
+
+func TestParseStockUpdate(t *testing.T) {
+	countedAt := time.Now().Add(-time.Hour)
+
+	reason, gotCountedAt, err := parseStockUpdate(&models.InventoryItemUpdate{CurrentStock: ptr(4), Reason: ptr("Waste"), CountedAt: &countedAt})
+	if err != nil || reason != ReasonWaste || !gotCountedAt.Equal(countedAt) {
+		t.Errorf("parseStockUpdate = %q, %v, %v; want waste at %v", reason, gotCountedAt, err, countedAt)
+	}
+
+	future := time.Now().Add(time.Hour)
+	invalid := []models.InventoryItemUpdate{
+		{CurrentStock: ptr(-1)},
+		{CurrentStock: ptr(1), CountedAt: &future},
+		{CurrentStock: ptr(1), Reason: ptr("lost")},
+	}
+	for _, update := range invalid {
+		if _, _, err := parseStockUpdate(&update); !errors.Is(err, ErrInvalidAdjustment) {
+			t.Errorf("parseStockUpdate(%+v) error = %v, want ErrInvalidAdjustment", update, err)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package squareUtils
+
+import (
+	"aoa-inventory/squareUtils/models"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	square "github.com/square/square-go-sdk"
+)
+
+// AllLocations can be used as an InventoryQuery location to read stock summed across every
+// active location, with a per-location breakdown on each item.
+const AllLocations = "all"
+
+var ErrLocationNotFound = errors.New("location not found")
+
+var ErrInvalidInventoryState = errors.New("invalid inventory state")
+
+// InventoryQuery selects what the read methods of an InventoryBackend return.
+type InventoryQuery struct {
+	// LocationID is the location to read stock at. Empty means the backend's default location.
+	LocationID string
+
+	// States, when set, adds a per-state quantity breakdown for these Square inventory states.
+	States []string
+
+	// IncludeArchived lists archived items too. Items looked up by SKU are returned either way.
+	IncludeArchived bool
+
+	// IncludeUncounted also lists variations that track inventory but have no count yet, with a
+	// stock of 0. Square only lists variations it has a count for.
+	IncludeUncounted bool
+}
+
+// ParseInventoryStates parses a comma-separated list of Square inventory states such as
+// "IN_STOCK,WASTE", ignoring case and duplicates.
+func ParseInventoryStates(raw string) ([]string, error) {
+	states := []string{}
+	seen := map[string]bool{}
+
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.ToUpper(strings.TrimSpace(part))
+		if part == "" || seen[part] {
+			continue
+		}
+
+		state, err := square.NewInventoryStateFromString(part)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidInventoryState, part)
+		}
+
+		seen[part] = true
+		states = append(states, string(state))
+	}
+
+	return states, nil
+}
+
+// InventoryBackend is the store the API reads and writes inventory through. Write methods take
+// the location to change stock at; an empty location ID means the backend's default location.
+type InventoryBackend interface {
+	// ListInventory returns every tracked inventory item.
+	ListInventory(ctx context.Context, query InventoryQuery) ([]models.InventoryItem, error)
+
+	// GetInventoryItem returns the item with the given SKU or ErrInventoryItemNotFound.
+	GetInventoryItem(ctx context.Context, sku string, query InventoryQuery) (*models.InventoryItem, error)
+
+	// GetInventoryItemByVariationID returns the item with the given variation ID or
+	// ErrInventoryItemNotFound.
+	GetInventoryItemByVariationID(ctx context.Context, variationID string, query InventoryQuery) (*models.InventoryItem, error)
+
+	// UpdateInventoryItem applies the update to the item with the given SKU.
+	UpdateInventoryItem(ctx context.Context, locationID, sku string, update *models.InventoryItemUpdate) (*models.InventoryItem, error)
+
+	// CatalogChanges returns the JSON names of the catalog fields the update would change on the
+	// item with the given SKU, compared the same way UpdateInventoryItem compares them.
+	CatalogChanges(ctx context.Context, locationID, sku string, update *models.InventoryItemUpdate) ([]string, error)
+
+	// AdjustInventoryItem changes the stock of the item with the given SKU by a signed, non-zero
+	// delta, recorded with the given reason. An empty reason picks the default for the direction.
+	AdjustInventoryItem(ctx context.Context, locationID, sku string, delta int, reason AdjustmentReason) (*models.InventoryItem, error)
+
+	// BatchUpdateInventory applies several stock changes at one location, reporting the outcome
+	// of each separately. It only returns an error when none of the changes could be attempted.
+	BatchUpdateInventory(ctx context.Context, locationID string, changes []models.StockChange) ([]StockChangeOutcome, error)
+
+	// CreateInventoryItem creates an item with one or more variations and their opening stock at
+	// one location, returning the new inventory item for each variation.
+	CreateInventoryItem(ctx context.Context, locationID string, item *models.NewInventoryItem) ([]models.InventoryItem, error)
+
+	// ListItems returns catalog items with their variations and the variations' stock nested.
+	ListItems(ctx context.Context, query InventoryQuery) ([]models.Item, error)
+
+	// DeleteInventoryItem archives the item the SKU belongs to, so it can be restored with an
+	// update. A hard delete removes the SKU for good instead.
+	DeleteInventoryItem(ctx context.Context, sku string, hard bool) error
+
+	// UploadInventoryItemImage attaches an image to the item or variation of the SKU and returns
+	// the item with its new image URL.
+	UploadInventoryItemImage(ctx context.Context, locationID, sku string, upload *ImageUpload) (*models.InventoryItem, error)
+
+	// InventoryHistory returns a page of the stock changes recorded for a SKU.
+	InventoryHistory(ctx context.Context, sku string, query HistoryQuery) (*models.InventoryHistory, error)
+
+	// ListLocations returns the locations stock can be read and changed at.
+	ListLocations(ctx context.Context) ([]models.Location, error)
+}
@@ -0,0 +1,210 @@
+package squareUtils
+
+import (
+	"aoa-inventory/audit"
+	"aoa-inventory/squareUtils/models"
+	"context"
+	"fmt"
+	"time"
+
+	square "github.com/square/square-go-sdk"
+)
+
+// StockChangeOutcome is the result of one change in a BatchUpdateInventory call: the updated
+// item, or the error that kept the change from being applied.
+type StockChangeOutcome struct {
+	SKU  string
+	Item *models.InventoryItem
+	Err  error
+}
+
+// parseStockChange validates one entry of a bulk update and returns its reason.
+func parseStockChange(change models.StockChange) (AdjustmentReason, error) {
+	if change.SKU == "" {
+		return "", fmt.Errorf("%w: sku is required", ErrInvalidAdjustment)
+	}
+
+	if (change.CurrentStock == nil) == (change.Delta == nil) {
+		return "", fmt.Errorf("%w: exactly one of currentStock and delta is required", ErrInvalidAdjustment)
+	}
+
+	if change.CurrentStock != nil && *change.CurrentStock < 0 {
+		return "", fmt.Errorf("%w: currentStock cannot be negative", ErrInvalidAdjustment)
+	}
+
+	if change.Delta != nil && *change.Delta == 0 {
+		return "", fmt.Errorf("%w: delta must be non-zero", ErrInvalidAdjustment)
+	}
+
+	reason := AdjustmentReason("")
+	if change.Reason != nil {
+		parsed, err := ParseAdjustmentReason(*change.Reason)
+		if err != nil {
+			return "", err
+		}
+		reason = parsed
+	}
+
+	if change.Delta != nil {
+		if err := reason.checkDirection(*change.Delta); err != nil {
+			return "", err
+		}
+	}
+
+	return reason, nil
+}
+
+// batchEntry is a validated change waiting to be sent to Square.
+type batchEntry struct {
+	index       int
+	variationID string
+	reason      AdjustmentReason
+	change      *square.InventoryChange
+}
+
+// BatchUpdateInventory resolves every SKU against one catalog snapshot and sends the changes in
+// chunks of at most maxChangesPerRequest. Square applies each chunk all or nothing, so a failed
+// request fails every change in its chunk and no other.
+func (b *SquareBackend) BatchUpdateInventory(ctx context.Context, locationID string, changes []models.StockChange) ([]StockChangeOutcome, error) {
+	locationID, err := b.resolveWriteLocation(ctx, locationID)
+	if err != nil {
+		return nil, err
+	}
+
+	idx, err := b.catalog.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	outcomes := make([]StockChangeOutcome, len(changes))
+	entries := []*batchEntry{}
+	seen := map[string]bool{}
+	refreshed := false
+
+	for i, change := range changes {
+		outcomes[i].SKU = change.SKU
+
+		reason, err := parseStockChange(change)
+		if err != nil {
+			outcomes[i].Err = err
+			continue
+		}
+
+		// Both changes would be computed from the same starting count.
+		if seen[change.SKU] {
+			outcomes[i].Err = fmt.Errorf("%w: sku %s appears more than once", ErrInvalidAdjustment, change.SKU)
+			continue
+		}
+		seen[change.SKU] = true
+
+		variationID, ok := idx.variationIDForSKU(change.SKU)
+		if !ok && !refreshed {
+			refreshed = true
+			if idx, err = b.catalog.getFresh(ctx, skuMissRefreshAge); err != nil {
+				return nil, err
+			}
+			variationID, ok = idx.variationIDForSKU(change.SKU)
+		}
+		if !ok {
+			outcomes[i].Err = ErrInventoryItemNotFound
+			continue
+		}
+
+		entries = append(entries, &batchEntry{index: i, variationID: variationID, reason: reason})
+
+		audit.Note(ctx, change.SKU, func(record *models.AuditRecord) {
+			record.VariationID, record.LocationID, record.Reason = variationID, locationID, string(reason)
+		})
+	}
+
+	// Reason-coded absolute sets are sent as adjustments, which need the current count. The
+	// audit log records it for every change.
+	currentCounts := map[string]*locationCount{}
+	variationIDs := make([]string, len(entries))
+	for i, entry := range entries {
+		variationIDs[i] = entry.variationID
+	}
+	if len(variationIDs) > 0 {
+		variationCounts, err := fetchInventoryCounts(ctx, []string{locationID}, variationIDs, []square.InventoryState{square.InventoryStateInStock})
+		if err != nil {
+			return nil, err
+		}
+		for variationID, locationCounts := range variationCounts {
+			currentCounts[variationID] = locationCounts[locationID]
+		}
+	}
+
+	now := time.Now()
+	queued := []*batchEntry{}
+	for _, entry := range entries {
+		change := changes[entry.index]
+
+		oldQty, _ := currentCounts[entry.variationID].inStock()
+		audit.Note(ctx, change.SKU, func(record *models.AuditRecord) { record.OldStock = &oldQty })
+
+		var err error
+		switch {
+		case change.Delta != nil:
+			entry.change, err = newAdjustmentChange(locationID, entry.variationID, *change.Delta, entry.reason, now)
+		case entry.reason != "":
+			currentQty, currentVersion := currentCounts[entry.variationID].inStock()
+			delta := *change.CurrentStock - currentQty
+			if delta == 0 {
+				item := idx.inventoryItem(entry.variationID, locationID, currentQty)
+				item.Version = currentVersion
+				outcomes[entry.index].Item = &item
+				audit.Note(ctx, change.SKU, func(record *models.AuditRecord) { record.NewStock = &currentQty })
+				continue
+			}
+			entry.change, err = newAdjustmentChange(locationID, entry.variationID, delta, entry.reason, now)
+		default:
+			entry.change = newPhysicalCountChange(locationID, entry.variationID, *change.CurrentStock, now)
+		}
+
+		if err != nil {
+			outcomes[entry.index].Err = err
+			continue
+		}
+		queued = append(queued, entry)
+	}
+
+	applied := 0
+	for start := 0; start < len(queued); start += maxChangesPerRequest {
+		chunk := queued[start:min(start+maxChangesPerRequest, len(queued))]
+
+		squareChanges := make([]*square.InventoryChange, len(chunk))
+		for i, entry := range chunk {
+			squareChanges[i] = entry.change
+		}
+
+		counts, err := submitInventoryChanges(ctx, locationID, squareChanges)
+		if err != nil {
+			log.Printf("ERROR: Failed to apply %d inventory changes: %v", len(chunk), err)
+			for _, entry := range chunk {
+				outcomes[entry.index].Err = err
+			}
+			continue
+		}
+		applied += len(chunk)
+
+		for _, entry := range chunk {
+			var qty int
+			var version string
+			if counts[entry.variationID] != nil {
+				qty, version = counts[entry.variationID].inStock()
+			} else if qty, version, err = fetchInventoryCount(ctx, locationID, entry.variationID); err != nil {
+				outcomes[entry.index].Err = fmt.Errorf("change was applied but the new count could not be read: %w", err)
+				continue
+			}
+
+			item := idx.inventoryItem(entry.variationID, locationID, qty)
+			item.Version = version
+			outcomes[entry.index].Item = &item
+			audit.Note(ctx, item.SKU, func(record *models.AuditRecord) { record.NewStock = &item.CurrentStock })
+		}
+	}
+
+	log.Printf("Applied %d of %d inventory changes", applied, len(changes))
+
+	return outcomes, nil
+}
@@ -0,0 +1,44 @@
+package squareUtils
+
+import (
+	"aoa-inventory/squareUtils/models"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestBatchUpdateInventory(t *testing.T) {
+	backend, _ := newFakeBackend(t)
+
+	outcomes, err := backend.BatchUpdateInventory(context.Background(), "", []models.StockChange{
+		{SKU: "LAT-001", CurrentStock: ptr(20)},
+		{SKU: "BAK-002", Delta: ptr(-1), Reason: ptr("waste")},
+		{SKU: "NOPE", Delta: ptr(1)},
+	})
+	if err != nil {
+		t.Fatalf("BatchUpdateInventory: %v", err)
+	}
+	if len(outcomes) != 3 {
+		t.Fatalf("got %d outcomes, want 3", len(outcomes))
+	}
+
+	want := map[string]int{"LAT-001": 20, "BAK-002": 3}
+	for _, outcome := range outcomes {
+		if outcome.SKU == "NOPE" {
+			if !errors.Is(outcome.Err, ErrInventoryItemNotFound) {
+				t.Errorf("unknown SKU: err = %v, want ErrInventoryItemNotFound", outcome.Err)
+			}
+			continue
+		}
+		if outcome.Err != nil {
+			t.Errorf("%s: %v", outcome.SKU, outcome.Err)
+			continue
+		}
+		if outcome.Item.CurrentStock != want[outcome.SKU] {
+			t.Errorf("%s: stock = %d, want %d", outcome.SKU, outcome.Item.CurrentStock, want[outcome.SKU])
+		}
+		if got := getItem(t, backend, outcome.SKU, "").CurrentStock; got != want[outcome.SKU] {
+			t.Errorf("%s: stock read back = %d, want %d", outcome.SKU, got, want[outcome.SKU])
+		}
+	}
+}
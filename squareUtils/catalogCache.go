@@ -0,0 +1,166 @@
+package squareUtils
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	square "github.com/square/square-go-sdk"
+)
+
+const backgroundRefreshTimeout = 2 * time.Minute
+
+// CatalogCache shares one catalog index between requests so the catalog is only paged
+// through when the cached copy expires or is invalidated. Expired entries are served
+// while a background refresh runs; only a missing index blocks the caller.
+type CatalogCache struct {
+	ttl time.Duration
+
+	mu         sync.RWMutex
+	index      *catalogIndex
+	fetchedAt  time.Time
+	generation uint64
+
+	refreshMu  sync.Mutex
+	refreshing atomic.Bool
+}
+
+func NewCatalogCache(ttl time.Duration) *CatalogCache {
+	return &CatalogCache{ttl: ttl}
+}
+
+// get returns the cached index, loading it synchronously if none is cached yet.
+func (c *CatalogCache) get(ctx context.Context) (*catalogIndex, error) {
+	c.mu.RLock()
+	index, fetchedAt := c.index, c.fetchedAt
+	c.mu.RUnlock()
+
+	if index == nil {
+		return c.refresh(ctx, time.Time{})
+	}
+
+	if time.Since(fetchedAt) >= c.ttl {
+		c.refreshInBackground()
+	}
+
+	return index, nil
+}
+
+// getFresh returns an index fetched no earlier than maxAge ago, refreshing synchronously if needed.
+func (c *CatalogCache) getFresh(ctx context.Context, maxAge time.Duration) (*catalogIndex, error) {
+	c.mu.RLock()
+	index, fetchedAt := c.index, c.fetchedAt
+	c.mu.RUnlock()
+
+	if index != nil && time.Since(fetchedAt) < maxAge {
+		return index, nil
+	}
+
+	return c.refresh(ctx, time.Now().Add(-maxAge))
+}
+
+// age reports how long ago the cached index was fetched.
+func (c *CatalogCache) age() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return time.Since(c.fetchedAt)
+}
+
+// Invalidate drops the cached index so the next request reloads the catalog.
+func (c *CatalogCache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.index = nil
+	c.fetchedAt = time.Time{}
+	c.generation++
+
+	log.Println("Catalog cache invalidated")
+}
+
+// apply adds objects this service just wrote to the cached index. A refresh already in flight
+// is discarded, since it may have started before the write.
+func (c *CatalogCache) apply(catalogObjects []*square.CatalogObject) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.index == nil {
+		return
+	}
+
+	c.index = c.index.withObjects(catalogObjects)
+	c.generation++
+}
+
+// InvalidateIfUnknown invalidates the cache when any of the variation IDs is missing from the
+// cached catalog, which happens when items are created outside this service.
+func (c *CatalogCache) InvalidateIfUnknown(variationIDs ...string) {
+	c.mu.RLock()
+	index := c.index
+	c.mu.RUnlock()
+
+	if index == nil {
+		return
+	}
+
+	for _, variationID := range variationIDs {
+		if _, ok := index.variationDetails[variationID]; !ok {
+			c.Invalidate()
+			return
+		}
+	}
+}
+
+// refresh fetches the catalog unless another caller already loaded one after notBefore.
+func (c *CatalogCache) refresh(ctx context.Context, notBefore time.Time) (*catalogIndex, error) {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	c.mu.RLock()
+	index, fetchedAt, generation := c.index, c.fetchedAt, c.generation
+	c.mu.RUnlock()
+
+	if index != nil && !fetchedAt.Before(notBefore) {
+		return index, nil
+	}
+
+	startedAt := time.Now()
+	catalogObjects, err := fetchAllCatalogObjects(ctx)
+	if err != nil {
+		log.Printf("ERROR: Failed to fetch catalog items from Square: %v", err)
+		return nil, err
+	}
+
+	index = newCatalogIndex(catalogObjects)
+
+	c.mu.Lock()
+	// An invalidation during the fetch means this copy may already be out of date.
+	if c.generation == generation {
+		c.index = index
+		c.fetchedAt = startedAt
+	}
+	c.mu.Unlock()
+
+	log.Printf("Loaded %d catalog objects into the catalog cache", len(catalogObjects))
+
+	return index, nil
+}
+
+func (c *CatalogCache) refreshInBackground() {
+	if !c.refreshing.CompareAndSwap(false, true) {
+		return
+	}
+
+	go func() {
+		defer c.refreshing.Store(false)
+
+		ctx, cancel := context.WithTimeout(context.Background(), backgroundRefreshTimeout)
+		defer cancel()
+
+		if _, err := c.refresh(ctx, time.Now()); err != nil {
+			log.Printf("ERROR: Background catalog refresh failed: %v", err)
+		}
+	}()
+}
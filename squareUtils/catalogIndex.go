@@ -0,0 +1,378 @@
+package squareUtils
+
+import (
+	"aoa-inventory/squareUtils/models"
+	"cmp"
+	"maps"
+	"slices"
+
+	square "github.com/square/square-go-sdk"
+)
+
+type itemMeta struct {
+	name                  string
+	desc                  string
+	categoryID            string
+	categoryName          string
+	reportingCategoryID   string
+	reportingCategoryName string
+	imageIDs              []string
+	archived              bool
+	version               int64
+}
+
+type variationMeta struct {
+	itemID        string
+	variationName string
+	sku           string
+	imageID       string
+	ordinal       int
+	options       []optionRef
+	price         variationPrice
+
+	alert   *models.InventoryAlert
+	tracked bool
+
+	// locationPrices, locationAlerts and locationTracked hold the location overrides by
+	// location ID.
+	locationPrices  map[string]variationPrice
+	locationAlerts  map[string]*models.InventoryAlert
+	locationTracked map[string]bool
+}
+
+// optionRef is the item option value a variation has, e.g. the "Large" value of "Size".
+type optionRef struct {
+	optionID string
+	valueID  string
+}
+
+// catalogIndex holds the lookups needed to turn Square catalog objects into inventory items.
+type catalogIndex struct {
+	imageURLs        map[string]string
+	categoryNames    map[string]string
+	itemsMeta        map[string]itemMeta
+	variationDetails map[string]variationMeta
+	skuToVariation   map[string]string
+	optionNames      map[string]string
+	optionValueNames map[string]string
+}
+
+func newCatalogIndex(catalogObjects []*square.CatalogObject) *catalogIndex {
+	idx := &catalogIndex{
+		imageURLs:        map[string]string{},
+		categoryNames:    map[string]string{},
+		itemsMeta:        map[string]itemMeta{},
+		variationDetails: map[string]variationMeta{},
+		skuToVariation:   map[string]string{},
+		optionNames:      map[string]string{},
+		optionValueNames: map[string]string{},
+	}
+
+	// Build maps from the catalog objects we received.
+	idx.add(catalogObjects)
+
+	return idx
+}
+
+// withObjects returns a copy of the index with the given objects added or replaced, so objects
+// this service just wrote are visible without reloading the whole catalog.
+func (idx *catalogIndex) withObjects(catalogObjects []*square.CatalogObject) *catalogIndex {
+	updated := &catalogIndex{
+		imageURLs:        maps.Clone(idx.imageURLs),
+		categoryNames:    maps.Clone(idx.categoryNames),
+		itemsMeta:        maps.Clone(idx.itemsMeta),
+		variationDetails: maps.Clone(idx.variationDetails),
+		skuToVariation:   maps.Clone(idx.skuToVariation),
+		optionNames:      maps.Clone(idx.optionNames),
+		optionValueNames: maps.Clone(idx.optionValueNames),
+	}
+
+	updated.add(catalogObjects)
+
+	return updated
+}
+
+func (idx *catalogIndex) add(catalogObjects []*square.CatalogObject) {
+	for _, obj := range catalogObjects {
+		if obj == nil {
+			continue
+		}
+
+		switch obj.GetType() {
+		case "IMAGE":
+			if obj.Image != nil && obj.Image.ImageData != nil && obj.Image.ImageData.URL != nil {
+				idx.imageURLs[obj.Image.ID] = *obj.Image.ImageData.URL
+			}
+		case "CATEGORY":
+			if obj.Category != nil && obj.Category.CategoryData != nil && obj.Category.CategoryData.Name != nil && obj.Category.ID != nil {
+				idx.categoryNames[*obj.Category.ID] = *obj.Category.CategoryData.Name
+			}
+		case "ITEM_OPTION":
+			if obj.ItemOption != nil && obj.ItemOption.ItemOptionData != nil {
+				if name := obj.ItemOption.ItemOptionData.Name; name != nil {
+					idx.optionNames[obj.ItemOption.ID] = *name
+				}
+				idx.add(obj.ItemOption.ItemOptionData.Values)
+			}
+		case "ITEM_OPTION_VAL":
+			if obj.ItemOptionVal != nil && obj.ItemOptionVal.ItemOptionValueData != nil && obj.ItemOptionVal.ItemOptionValueData.Name != nil {
+				idx.optionValueNames[obj.ItemOptionVal.ID] = *obj.ItemOptionVal.ItemOptionValueData.Name
+			}
+		case "ITEM":
+			if obj.Item != nil && obj.Item.ItemData != nil {
+				itemData := obj.Item.ItemData
+				meta := itemMeta{}
+				if itemData.Name != nil {
+					meta.name = *itemData.Name
+				}
+				if itemData.Description != nil {
+					meta.desc = *itemData.Description
+				}
+				if itemData.CategoryID != nil {
+					meta.categoryID = *itemData.CategoryID
+				} else if len(itemData.Categories) > 0 && itemData.Categories[0] != nil && itemData.Categories[0].CategoryData != nil && itemData.Categories[0].CategoryData.Name != nil {
+					if name := itemData.Categories[0].CategoryData.GetName(); name != nil {
+						meta.categoryName = *name
+					}
+				}
+				if itemData.ReportingCategory != nil {
+					if itemData.ReportingCategory.ID != nil {
+						meta.reportingCategoryID = *itemData.ReportingCategory.ID
+					}
+					if itemData.ReportingCategory.CategoryData != nil && itemData.ReportingCategory.CategoryData.Name != nil {
+						meta.reportingCategoryName = *itemData.ReportingCategory.CategoryData.Name
+					} else if extra := itemData.ReportingCategory.GetExtraProperties(); extra != nil {
+						if name, ok := extra["name"].(string); ok {
+							meta.reportingCategoryName = name
+						}
+					}
+				}
+				if len(itemData.ImageIDs) > 0 {
+					meta.imageIDs = itemData.ImageIDs
+				}
+				if itemData.IsArchived != nil {
+					meta.archived = *itemData.IsArchived
+				}
+				if obj.Item.Version != nil {
+					meta.version = *obj.Item.Version
+				}
+				idx.itemsMeta[obj.Item.ID] = meta
+
+				// Items embed their variations when they are upserted.
+				idx.add(itemData.Variations)
+			}
+		case "ITEM_VARIATION":
+			if obj.ItemVariation != nil && obj.ItemVariation.ItemVariationData != nil {
+				vData := obj.ItemVariation.ItemVariationData
+				meta := variationMeta{}
+				if vData.ItemID != nil {
+					meta.itemID = *vData.ItemID
+				}
+				if vData.Name != nil {
+					meta.variationName = *vData.Name
+				}
+				if vData.Sku != nil && *vData.Sku != "" {
+					meta.sku = *vData.Sku
+				} else {
+					meta.sku = obj.ItemVariation.ID
+				}
+				if obj.ItemVariation.ImageID != nil {
+					meta.imageID = *obj.ItemVariation.ImageID
+				} else if len(vData.ImageIDs) > 0 {
+					meta.imageID = vData.ImageIDs[0]
+				}
+				meta.price = variationPrice{money: vData.PriceMoney}
+				if vData.PricingType != nil {
+					meta.price.pricingType = *vData.PricingType
+				}
+				meta.alert = squareInventoryAlert(vData.InventoryAlertType, vData.InventoryAlertThreshold)
+				meta.tracked = vData.TrackInventory != nil && *vData.TrackInventory
+				for _, override := range vData.LocationOverrides {
+					if override == nil || override.LocationID == nil {
+						continue
+					}
+					if override.PricingType != nil || override.PriceMoney != nil {
+						if meta.locationPrices == nil {
+							meta.locationPrices = map[string]variationPrice{}
+						}
+						locationPrice := variationPrice{money: override.PriceMoney}
+						if override.PricingType != nil {
+							locationPrice.pricingType = *override.PricingType
+						}
+						meta.locationPrices[*override.LocationID] = locationPrice
+					}
+					if alert := squareInventoryAlert(override.InventoryAlertType, override.InventoryAlertThreshold); alert != nil {
+						if meta.locationAlerts == nil {
+							meta.locationAlerts = map[string]*models.InventoryAlert{}
+						}
+						meta.locationAlerts[*override.LocationID] = alert
+					}
+					if override.TrackInventory != nil {
+						if meta.locationTracked == nil {
+							meta.locationTracked = map[string]bool{}
+						}
+						meta.locationTracked[*override.LocationID] = *override.TrackInventory
+					}
+				}
+				if vData.Ordinal != nil {
+					meta.ordinal = *vData.Ordinal
+				}
+				for _, option := range vData.ItemOptionValues {
+					if option != nil && option.ItemOptionID != nil && option.ItemOptionValueID != nil {
+						meta.options = append(meta.options, optionRef{optionID: *option.ItemOptionID, valueID: *option.ItemOptionValueID})
+					}
+				}
+				if previous, ok := idx.variationDetails[obj.ItemVariation.ID]; ok && previous.sku != meta.sku && idx.skuToVariation[previous.sku] == obj.ItemVariation.ID {
+					delete(idx.skuToVariation, previous.sku)
+				}
+				idx.variationDetails[obj.ItemVariation.ID] = meta
+				idx.skuToVariation[meta.sku] = obj.ItemVariation.ID
+			}
+		}
+	}
+}
+
+// variationIDForSKU returns the variation ID for the given SKU, if the catalog has one.
+func (idx *catalogIndex) variationIDForSKU(sku string) (string, bool) {
+	variationID, ok := idx.skuToVariation[sku]
+	return variationID, ok
+}
+
+// inventoryItem builds the API model for a variation using the indexed catalog data, priced at
+// the location. An empty locationID gives the base price.
+func (idx *catalogIndex) inventoryItem(variationID, locationID string, stock int) models.InventoryItem {
+	meta := idx.variationDetails[variationID]
+	parent := idx.item(meta.itemID)
+
+	name := parent.Name
+	if name == "" {
+		name = meta.variationName
+	}
+	if name == "" {
+		name = variationID
+	}
+
+	return models.InventoryItem{
+		ID:                variationID,
+		Name:              name,
+		VariationName:     meta.variationName,
+		Description:       parent.Description,
+		SKU:               meta.sku,
+		CurrentStock:      stock,
+		ImageURL:          idx.variationImageURL(variationID),
+		Category:          parent.Category,
+		ReportingCategory: parent.ReportingCategory,
+		Archived:          parent.Archived,
+		Price:             idx.price(variationID, locationID),
+		InventoryAlert:    idx.inventoryAlert(variationID, locationID),
+		CatalogVersion:    idx.itemsMeta[meta.itemID].version,
+	}
+}
+
+// item builds the API model for an item without its variations.
+func (idx *catalogIndex) item(itemID string) models.Item {
+	parent := idx.itemsMeta[itemID]
+
+	imageURL := ""
+	if len(parent.imageIDs) > 0 {
+		imageURL = idx.imageURLs[parent.imageIDs[0]]
+	}
+
+	categoryName := ""
+	if parent.categoryID != "" {
+		categoryName = idx.categoryNames[parent.categoryID]
+	} else if parent.categoryName != "" {
+		categoryName = parent.categoryName
+	}
+
+	reportingCategory := parent.reportingCategoryName
+	if reportingCategory == "" && parent.reportingCategoryID != "" {
+		reportingCategory = idx.categoryNames[parent.reportingCategoryID]
+		if reportingCategory == "" {
+			reportingCategory = parent.reportingCategoryID
+		}
+	}
+
+	displayCategory := categoryName
+	if displayCategory == "" {
+		displayCategory = reportingCategory
+	}
+
+	return models.Item{
+		ID:                itemID,
+		Name:              parent.name,
+		Description:       parent.desc,
+		ImageURL:          imageURL,
+		Category:          displayCategory,
+		ReportingCategory: reportingCategory,
+		Archived:          parent.archived,
+		Variations:        []models.Variation{},
+	}
+}
+
+// variationImageURL prefers the variation's own image, then the first image of its item.
+func (idx *catalogIndex) variationImageURL(variationID string) string {
+	meta := idx.variationDetails[variationID]
+	if meta.imageID != "" {
+		if url, ok := idx.imageURLs[meta.imageID]; ok {
+			return url
+		}
+	}
+
+	if parent := idx.itemsMeta[meta.itemID]; len(parent.imageIDs) > 0 {
+		return idx.imageURLs[parent.imageIDs[0]]
+	}
+	return ""
+}
+
+// variationsByItem groups variation IDs by item, in the order Square shows them.
+func (idx *catalogIndex) variationsByItem() map[string][]string {
+	grouped := map[string][]string{}
+	for variationID, meta := range idx.variationDetails {
+		grouped[meta.itemID] = append(grouped[meta.itemID], variationID)
+	}
+
+	for _, variationIDs := range grouped {
+		slices.SortFunc(variationIDs, func(a, b string) int {
+			metaA, metaB := idx.variationDetails[a], idx.variationDetails[b]
+			return cmp.Or(cmp.Compare(metaA.ordinal, metaB.ordinal), cmp.Compare(metaA.variationName, metaB.variationName), cmp.Compare(a, b))
+		})
+	}
+
+	return grouped
+}
+
+// optionValues returns the named item option values of a variation.
+func (idx *catalogIndex) optionValues(variationID string) []models.OptionValue {
+	values := []models.OptionValue{}
+	for _, option := range idx.variationDetails[variationID].options {
+		values = append(values, models.OptionValue{Option: idx.optionNames[option.optionID], Value: idx.optionValueNames[option.valueID]})
+	}
+	return values
+}
+
+// isArchived reports whether the variation belongs to an archived item.
+// tracksInventory reports whether the variation tracks inventory at the location, following its
+// override there if it has one.
+func (idx *catalogIndex) tracksInventory(variationID, locationID string) bool {
+	meta := idx.variationDetails[variationID]
+	if tracked, ok := meta.locationTracked[locationID]; ok {
+		return tracked
+	}
+	return meta.tracked
+}
+
+func (idx *catalogIndex) isArchived(variationID string) bool {
+	return idx.itemsMeta[idx.variationDetails[variationID].itemID].archived
+}
+
+// variationCount returns how many variations of the item the index holds.
+func (idx *catalogIndex) variationCount(itemID string) int {
+	count := 0
+	for _, meta := range idx.variationDetails {
+		if meta.itemID == itemID {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,282 @@
+package squareUtils
+
+import (
+	"aoa-inventory/squareUtils/models"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	square "github.com/square/square-go-sdk"
+)
+
+var ErrInvalidItemUpdate = errors.New("invalid item update")
+
+var ErrCatalogConflict = errors.New("catalog item was changed by someone else, please retry")
+
+// HasCatalogFields reports whether the update touches anything besides stock.
+func HasCatalogFields(update *models.InventoryItemUpdate) bool {
+	return update.ID != nil || update.Name != nil || update.Description != nil || update.ImageURL != nil ||
+		update.Category != nil || update.ReportingCategory != nil || update.Archived != nil || update.Price != nil ||
+		update.InventoryAlert != nil
+}
+
+// changedCatalogFields returns the JSON names of the catalog fields the update would change on
+// the item as read at the location. Clients often send back the whole item they read, so
+// unchanged values are not counted. A price update is compared with basePrice, the variation's
+// price without location overrides, or with current's price when it is for the location only.
+func changedCatalogFields(update *models.InventoryItemUpdate, current *models.InventoryItem, basePrice *models.Price) []string {
+	alert := update.InventoryAlert
+	if normalized, err := normalizeInventoryAlert(alert); err == nil {
+		alert = normalized
+	}
+
+	currentPrice := current.Price
+	if update.Price != nil && !update.Price.AtLocation {
+		currentPrice = basePrice
+	}
+
+	changes := []struct {
+		field   string
+		changed bool
+	}{
+		{"id", update.ID != nil && *update.ID != current.ID},
+		{"name", update.Name != nil && *update.Name != current.Name},
+		{"description", update.Description != nil && *update.Description != current.Description},
+		{"imageUrl", update.ImageURL != nil && *update.ImageURL != current.ImageURL},
+		{"category", update.Category != nil && *update.Category != current.Category},
+		{"reportingCategory", update.ReportingCategory != nil && *update.ReportingCategory != current.ReportingCategory},
+		{"archived", update.Archived != nil && *update.Archived != current.Archived},
+		{"price", update.Price != nil && !priceUnchanged(update.Price, currentPrice)},
+		{"inventoryAlert", alert != nil && !alertUnchanged(alert, current.InventoryAlert)},
+	}
+
+	fields := []string{}
+	for _, change := range changes {
+		if change.changed {
+			fields = append(fields, change.field)
+		}
+	}
+	return fields
+}
+
+func (b *SquareBackend) CatalogChanges(ctx context.Context, locationID, sku string, update *models.InventoryItemUpdate) ([]string, error) {
+	if !HasCatalogFields(update) {
+		return []string{}, nil
+	}
+
+	locationID, err := b.resolveWriteLocation(ctx, locationID)
+	if err != nil {
+		return nil, err
+	}
+
+	idx, variationID, err := b.resolveSKU(ctx, sku)
+	if err != nil {
+		return nil, err
+	}
+
+	_, fresh, err := fetchCatalogItem(ctx, idx.variationDetails[variationID].itemID, variationID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, changed := catalogChanges(fresh, variationID, locationID, update)
+	return changed, nil
+}
+
+// catalogChanges returns the variation's catalog fields at the location and which of them the
+// update would change. A location price update only matches an override, not the base price
+// showing through.
+func catalogChanges(idx *catalogIndex, variationID, locationID string, update *models.InventoryItemUpdate) (models.InventoryItem, []string) {
+	current := idx.inventoryItem(variationID, locationID, 0)
+	current.Price = idx.locationPrice(variationID, locationID)
+	return current, changedCatalogFields(update, &current, idx.price(variationID, ""))
+}
+
+// fetchCatalogItem re-reads the ITEM with the given ID from Square and indexes it, together with
+// the objects its variation references, so changes are compared with the catalog as it is now.
+func fetchCatalogItem(ctx context.Context, itemID, variationID string) (*square.CatalogObject, *catalogIndex, error) {
+	obj, err := fetchCatalogObject(ctx, itemID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if obj.Item == nil || obj.Item.ItemData == nil {
+		return nil, nil, ErrInventoryItemNotFound
+	}
+
+	variation := embeddedVariation(obj.Item.ItemData, variationID)
+	if variation == nil {
+		return nil, nil, ErrInventoryItemNotFound
+	}
+
+	idx, err := variationIndex(ctx, variation, []*square.CatalogObject{obj})
+	if err != nil {
+		return nil, nil, err
+	}
+	return obj, idx, nil
+}
+
+// embeddedVariation returns the variation with the given ID from the item's variations, or nil.
+func embeddedVariation(itemData *square.CatalogItem, variationID string) *square.CatalogObject {
+	i := slices.IndexFunc(itemData.Variations, func(variation *square.CatalogObject) bool {
+		return variation.ItemVariation != nil && variation.ItemVariation.ID == variationID
+	})
+	if i < 0 || itemData.Variations[i].ItemVariation.ItemVariationData == nil {
+		return nil
+	}
+	return itemData.Variations[i]
+}
+
+// categoryIDForName returns the ID of the category with the given name, ignoring case.
+func (idx *catalogIndex) categoryIDForName(name string) (string, bool) {
+	for categoryID, categoryName := range idx.categoryNames {
+		if strings.EqualFold(categoryName, name) {
+			return categoryID, true
+		}
+	}
+	return "", false
+}
+
+// categoryRefs turns category names into IDs for an upsert, adding a CATEGORY object with a
+// temporary ID for each name the catalog does not have yet.
+type categoryRefs struct {
+	idx     *catalogIndex
+	newIDs  map[string]string
+	created []*square.CatalogObject
+}
+
+func (r *categoryRefs) id(name string) string {
+	if categoryID, ok := r.idx.categoryIDForName(name); ok {
+		return categoryID
+	}
+	if categoryID, ok := r.newIDs[strings.ToLower(name)]; ok {
+		return categoryID
+	}
+
+	if r.newIDs == nil {
+		r.newIDs = map[string]string{}
+	}
+	categoryID := "#category-" + strconv.Itoa(len(r.newIDs)+1)
+	r.newIDs[strings.ToLower(name)] = categoryID
+	r.created = append(r.created, &square.CatalogObject{
+		Type: "CATEGORY",
+		Category: &square.CatalogObjectCategory{
+			ID:           square.String(categoryID),
+			CategoryData: &square.CatalogCategory{Name: square.String(name)},
+		},
+	})
+	return categoryID
+}
+
+// setCategory makes name the item's only category, or clears it when name is empty.
+func (r *categoryRefs) setCategory(itemData *square.CatalogItem, name string) {
+	itemData.CategoryID = nil
+	itemData.Categories = nil
+	if name != "" {
+		categoryID := r.id(name)
+		itemData.CategoryID = square.String(categoryID)
+		itemData.Categories = []*square.CatalogObjectCategory{{ID: square.String(categoryID)}}
+	}
+}
+
+func (r *categoryRefs) setReportingCategory(itemData *square.CatalogItem, name string) {
+	itemData.ReportingCategory = nil
+	if name != "" {
+		itemData.ReportingCategory = &square.CatalogObjectCategory{ID: square.String(r.id(name))}
+	}
+}
+
+// updateCatalogItem writes the catalog fields of the update to the variation's parent ITEM and
+// returns an index that includes the change. The update is compared with the item as re-read
+// from Square, which is then upserted with the version it was read at, so an edit made in
+// between fails with ErrCatalogConflict instead of being overwritten. The same happens when the
+// update expects an older catalog version. Unknown category names create new categories. A price
+// is written to the variation itself, or to its override at locationID.
+func (b *SquareBackend) updateCatalogItem(ctx context.Context, idx *catalogIndex, locationID, variationID string, update *models.InventoryItemUpdate) (*catalogIndex, error) {
+	itemID := idx.variationDetails[variationID].itemID
+	obj, fresh, err := fetchCatalogItem(ctx, itemID, variationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.ExpectedCatalogVersion != nil && *update.ExpectedCatalogVersion != fresh.itemsMeta[itemID].version {
+		return nil, ErrCatalogConflict
+	}
+
+	current, changed := catalogChanges(fresh, variationID, locationID, update)
+
+	// Clients often send back the whole item they read, so unchanged values are accepted.
+	if update.ID != nil && *update.ID != current.ID {
+		return nil, fmt.Errorf("%w: id cannot be changed", ErrInvalidItemUpdate)
+	}
+	if update.ImageURL != nil && *update.ImageURL != current.ImageURL {
+		return nil, fmt.Errorf("%w: imageUrl cannot be changed in Square", ErrInvalidItemUpdate)
+	}
+
+	if len(changed) == 0 {
+		return idx, nil
+	}
+
+	if update.Name != nil && strings.TrimSpace(*update.Name) == "" {
+		return nil, fmt.Errorf("%w: name cannot be empty", ErrInvalidItemUpdate)
+	}
+
+	itemData := obj.Item.ItemData
+	objects := []*square.CatalogObject{obj}
+
+	if update.Name != nil {
+		itemData.Name = square.String(*update.Name)
+	}
+
+	if update.Description != nil {
+		// Square prefers description_html when both are set.
+		itemData.Description = square.String(*update.Description)
+		itemData.DescriptionHTML = nil
+	}
+
+	if update.Archived != nil {
+		itemData.IsArchived = square.Bool(*update.Archived)
+	}
+
+	variationData := embeddedVariation(itemData, variationID).ItemVariation.ItemVariationData
+
+	if slices.Contains(changed, "price") {
+		var money *square.Money
+		if update.Price.Amount != nil {
+			currency, err := b.locationCurrency(ctx, locationID)
+			if err != nil {
+				return nil, err
+			}
+			if money, err = squareMoney(*update.Price.Amount, update.Price.Currency, currency); err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrInvalidItemUpdate, err)
+			}
+		}
+
+		setVariationPrice(variationData, locationID, update.Price.AtLocation, money)
+	}
+
+	if update.InventoryAlert != nil && !alertUnchanged(update.InventoryAlert, current.InventoryAlert) {
+		setInventoryAlert(variationData, locationID, update.InventoryAlert)
+	}
+
+	// Category names are looked up in the whole catalog, not just the categories the item uses.
+	categories := &categoryRefs{idx: idx}
+	if update.Category != nil && *update.Category != current.Category {
+		categories.setCategory(itemData, *update.Category)
+	}
+	if update.ReportingCategory != nil && *update.ReportingCategory != current.ReportingCategory {
+		categories.setReportingCategory(itemData, *update.ReportingCategory)
+	}
+
+	upserted, err := upsertCatalogObjects(ctx, append(objects, categories.created...))
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Updated catalog item %s", itemID)
+
+	b.catalog.apply(upserted)
+	return idx.withObjects(upserted), nil
+}
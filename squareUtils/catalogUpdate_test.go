@@ -0,0 +1,132 @@
+package squareUtils
+
+import (
+	"aoa-inventory/squareUtils/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func priceAmount(price *models.Price) int64 {
+	if price == nil || price.Amount == nil {
+		return -1
+	}
+	return *price.Amount
+}
+
+func TestUpdateBasePriceIgnoresLocationOverride(t *testing.T) {
+	backend, _ := newFakeBackend(t)
+
+	updateItem(t, backend, "LAT-001", &models.InventoryItemUpdate{Price: &models.PriceUpdate{Amount: ptr[int64](450)}})
+	updateItem(t, backend, "LAT-001", &models.InventoryItemUpdate{Price: &models.PriceUpdate{Amount: ptr[int64](500), AtLocation: true}})
+
+	// The override already is 500, but the base price is not.
+	updateItem(t, backend, "LAT-001", &models.InventoryItemUpdate{Price: &models.PriceUpdate{Amount: ptr[int64](500)}})
+
+	if got := priceAmount(getItem(t, backend, "LAT-001", AllLocations).Price); got != 500 {
+		t.Errorf("base price = %d, want 500", got)
+	}
+}
+
+func TestUpdateLocationPriceIgnoresBasePrice(t *testing.T) {
+	backend, _ := newFakeBackend(t)
+
+	updateItem(t, backend, "LAT-001", &models.InventoryItemUpdate{Price: &models.PriceUpdate{Amount: ptr[int64](450)}})
+
+	// The base price already is 450 at the location, but there is no override yet.
+	updateItem(t, backend, "LAT-001", &models.InventoryItemUpdate{Price: &models.PriceUpdate{Amount: ptr[int64](450), AtLocation: true}})
+	updateItem(t, backend, "LAT-001", &models.InventoryItemUpdate{Price: &models.PriceUpdate{Amount: ptr[int64](400)}})
+
+	if got := priceAmount(getItem(t, backend, "LAT-001", "").Price); got != 450 {
+		t.Errorf("price at location = %d, want the override 450", got)
+	}
+	if got := priceAmount(getItem(t, backend, "LAT-001", AllLocations).Price); got != 400 {
+		t.Errorf("base price = %d, want 400", got)
+	}
+}
+
+func TestChangedCatalogFieldsComparesPriceByTarget(t *testing.T) {
+	current := &models.InventoryItem{Price: &models.Price{PricingType: "FIXED_PRICING", Amount: ptr[int64](500), Currency: "USD"}}
+	base := &models.Price{PricingType: "FIXED_PRICING", Amount: ptr[int64](450), Currency: "USD"}
+
+	tests := []struct {
+		name  string
+		price models.PriceUpdate
+		want  int
+	}{
+		{"base update matching the override", models.PriceUpdate{Amount: ptr[int64](500)}, 1},
+		{"base update matching the base", models.PriceUpdate{Amount: ptr[int64](450)}, 0},
+		{"location update matching the override", models.PriceUpdate{Amount: ptr[int64](500), AtLocation: true}, 0},
+		{"location update matching the base", models.PriceUpdate{Amount: ptr[int64](450), AtLocation: true}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := changedCatalogFields(&models.InventoryItemUpdate{Price: &tt.price}, current, base)
+			if len(fields) != tt.want {
+				t.Errorf("changedCatalogFields = %v, want %d changed", fields, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateCatalogComparesWithSquareNotCache(t *testing.T) {
+	backend, server := newFakeBackend(t)
+	other := NewSquareBackend(server.LocationID, NewCatalogCache(time.Minute))
+
+	// Loads the catalog into the cache before the other backend renames the item.
+	if _, err := backend.ListInventory(context.Background(), InventoryQuery{}); err != nil {
+		t.Fatalf("ListInventory: %v", err)
+	}
+	updateItem(t, other, "LAT-001", &models.InventoryItemUpdate{Name: ptr("Iced Latte")})
+
+	// The cache still has the old name, so this only works when compared with Square.
+	updateItem(t, backend, "LAT-001", &models.InventoryItemUpdate{Name: ptr("Vanilla Latte")})
+
+	if got := getItem(t, other, "LAT-001", "").Name; got != "Vanilla Latte" {
+		t.Errorf("name = %q, want Vanilla Latte", got)
+	}
+}
+
+func TestUpdateCatalogExpectedVersion(t *testing.T) {
+	backend, _ := newFakeBackend(t)
+
+	read := getItem(t, backend, "LAT-001", "")
+	if read.CatalogVersion == 0 {
+		t.Fatal("item has no catalog version")
+	}
+
+	updated := updateItem(t, backend, "LAT-001", &models.InventoryItemUpdate{
+		Name:                   ptr("Iced Latte"),
+		ExpectedCatalogVersion: ptr(read.CatalogVersion),
+	})
+	if updated.CatalogVersion == read.CatalogVersion {
+		t.Errorf("catalog version was not bumped by the update")
+	}
+
+	_, err := backend.UpdateInventoryItem(context.Background(), "", "LAT-001", &models.InventoryItemUpdate{
+		Name:                   ptr("Hot Latte"),
+		ExpectedCatalogVersion: ptr(read.CatalogVersion),
+	})
+	if !errors.Is(err, ErrCatalogConflict) {
+		t.Fatalf("update with a stale catalog version: err = %v, want ErrCatalogConflict", err)
+	}
+	if got := getItem(t, backend, "LAT-001", "").Name; got != "Iced Latte" {
+		t.Errorf("name = %q after the rejected update, want Iced Latte", got)
+	}
+}
+
+func TestUpdateInventoryItemWritesCatalogFields(t *testing.T) {
+	backend, _ := newFakeBackend(t)
+
+	updateItem(t, backend, "LAT-001", &models.InventoryItemUpdate{Name: ptr("Oat Latte"), Category: ptr("Seasonal")})
+
+	item := getItem(t, backend, "LAT-001", "")
+	if item.Name != "Oat Latte" || item.Category != "Seasonal" {
+		t.Errorf("item = %q in %q, want Oat Latte in Seasonal", item.Name, item.Category)
+	}
+	if item.CurrentStock != 10 {
+		t.Errorf("stock = %d, want it unchanged at 10", item.CurrentStock)
+	}
+}
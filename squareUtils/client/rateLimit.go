@@ -0,0 +1,52 @@
+package client
+
+import (
+	"aoa-inventory/utils"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// RateLimitError is returned for Square calls the outbound limiter turned away because they
+// would have waited longer than allowed. RetryAfter is when a call would go through again.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("square rate limit reached, retry after %s", e.RetryAfter.Round(time.Second))
+}
+
+// rateLimitedTransport spaces out requests to Square so every caller of the shared client
+// together stays under the limit. Requests wait for their turn up to maxWait.
+type rateLimitedTransport struct {
+	base    http.RoundTripper
+	bucket  *utils.TokenBucket
+	maxWait time.Duration
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A cancelled request would otherwise use up a token that a live one could have had.
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+
+	wait, ok := t.bucket.Reserve(t.maxWait)
+	if !ok {
+		log.Printf("ERROR: Rejected Square request to %s, the outbound rate limit is exhausted", req.URL.Path)
+		return nil, &RateLimitError{RetryAfter: wait}
+	}
+
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	return t.base.RoundTrip(req)
+}
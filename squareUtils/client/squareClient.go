@@ -2,6 +2,8 @@ package client
 
 import (
 	"aoa-inventory/utils"
+	"net/http"
+	"time"
 
 	square "github.com/square/square-go-sdk"
 	client "github.com/square/square-go-sdk/client"
@@ -12,26 +14,44 @@ var log = utils.NewLogger("SQUARE-CLIENT")
 
 var SquareClient *client.Client
 
-func Init(accessToken, env string) {
+// maxRateLimitWait is the longest a Square call waits for the outbound rate limiter before it
+// fails with a RateLimitError.
+const maxRateLimitWait = 5 * time.Second
+
+// Init creates the shared Square client. A non-empty baseURL overrides the URL implied by env,
+// which lets the service run against a local fake Square server. Calls are limited to rate per
+// second on average with bursts of up to burst calls.
+func Init(accessToken, env, baseURL string, rate float64, burst int) {
 	if SquareClient != nil {
 		log.Println("WARNING: Square client already initialized, skipping...")
 		return
 	}
 
-	var envUrl string
-	switch env {
-	case "production":
-		envUrl = square.Environments.Production
-	case "sandbox":
-		envUrl = square.Environments.Sandbox
-	default:
-		log.Fatalln("ERROR: Invalid Square environment, exiting...")
+	envUrl := baseURL
+	if envUrl == "" {
+		switch env {
+		case "production":
+			envUrl = square.Environments.Production
+		case "sandbox":
+			envUrl = square.Environments.Sandbox
+		default:
+			log.Fatalln("ERROR: Invalid Square environment, exiting...")
+		}
+	}
+
+	httpClient := &http.Client{
+		Transport: &rateLimitedTransport{
+			base:    http.DefaultTransport,
+			bucket:  utils.NewTokenBucket(rate, burst),
+			maxWait: maxRateLimitWait,
+		},
 	}
 
 	SquareClient = client.NewClient(
 		option.WithToken(accessToken),
 		option.WithBaseURL(envUrl),
+		option.WithHTTPClient(httpClient),
 	)
 
-	log.Printf("Initialized Square client in %s environment", env)
+	log.Printf("Initialized Square client in %s environment at %s", env, envUrl)
 }
@@ -0,0 +1,206 @@
+package squareUtils
+
+import (
+	"aoa-inventory/audit"
+	"aoa-inventory/squareUtils/models"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	square "github.com/square/square-go-sdk"
+)
+
+var ErrInvalidNewItem = errors.New("invalid item")
+
+var ErrDuplicateSKU = errors.New("sku already exists")
+
+// defaultCurrency prices items when neither the request nor the location names a currency.
+const defaultCurrency = "USD"
+
+// newItemVariations validates a create request and returns its variations. An item without
+// variations gets a single "Regular" variation from the top-level SKU, price and stock.
+func newItemVariations(item *models.NewInventoryItem) ([]models.NewVariation, error) {
+	if item == nil || strings.TrimSpace(item.Name) == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidNewItem)
+	}
+
+	variations := slices.Clone(item.Variations)
+	if len(variations) == 0 {
+		variations = []models.NewVariation{{Name: "Regular", SKU: item.SKU, Price: item.Price, CurrentStock: item.CurrentStock}}
+	} else if item.SKU != "" || item.Price != nil || item.CurrentStock != 0 {
+		return nil, fmt.Errorf("%w: sku, price and currentStock go on each variation when variations are given", ErrInvalidNewItem)
+	}
+
+	seen := map[string]bool{}
+	for i, variation := range variations {
+		if variation.SKU == "" {
+			return nil, fmt.Errorf("%w: sku is required", ErrInvalidNewItem)
+		}
+		if seen[variation.SKU] {
+			return nil, fmt.Errorf("%w: sku %s appears more than once", ErrInvalidNewItem, variation.SKU)
+		}
+		seen[variation.SKU] = true
+
+		if variation.CurrentStock < 0 {
+			return nil, fmt.Errorf("%w: currentStock cannot be negative", ErrInvalidNewItem)
+		}
+		if variation.Price != nil && variation.Price.Amount < 0 {
+			return nil, fmt.Errorf("%w: price cannot be negative", ErrInvalidNewItem)
+		}
+		if variation.Name == "" {
+			variations[i].Name = "Regular"
+		}
+	}
+
+	return variations, nil
+}
+
+// CreateInventoryItem creates an ITEM with its variations, tracks their inventory at the location
+// and records the opening counts. It returns one inventory item per variation.
+func (b *SquareBackend) CreateInventoryItem(ctx context.Context, locationID string, newItem *models.NewInventoryItem) ([]models.InventoryItem, error) {
+	variations, err := newItemVariations(newItem)
+	if err != nil {
+		return nil, err
+	}
+
+	locationID, err = b.resolveWriteLocation(ctx, locationID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Square accepts duplicate SKUs, so the catalog is checked here.
+	idx, err := b.catalog.getFresh(ctx, skuMissRefreshAge)
+	if err != nil {
+		return nil, err
+	}
+	for _, variation := range variations {
+		if _, ok := idx.variationIDForSKU(variation.SKU); ok {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateSKU, variation.SKU)
+		}
+	}
+
+	currency, err := b.locationCurrency(ctx, locationID)
+	if err != nil {
+		return nil, err
+	}
+
+	itemData := &square.CatalogItem{Name: square.String(newItem.Name)}
+	if newItem.Description != "" {
+		itemData.Description = square.String(newItem.Description)
+	}
+
+	categories := &categoryRefs{idx: idx}
+	categories.setCategory(itemData, newItem.Category)
+	categories.setReportingCategory(itemData, newItem.ReportingCategory)
+
+	for i, variation := range variations {
+		variationData := &square.CatalogItemVariation{
+			ItemID:         square.String("#item"),
+			Name:           square.String(variation.Name),
+			Sku:            square.String(variation.SKU),
+			Ordinal:        square.Int(i),
+			PricingType:    square.CatalogPricingTypeVariablePricing.Ptr(),
+			TrackInventory: square.Bool(true),
+			LocationOverrides: []*square.ItemVariationLocationOverrides{{
+				LocationID:     square.String(locationID),
+				TrackInventory: square.Bool(true),
+			}},
+		}
+		if variation.Price != nil {
+			money, err := squareMoney(variation.Price.Amount, variation.Price.Currency, currency)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrInvalidNewItem, err)
+			}
+			variationData.PricingType = square.CatalogPricingTypeFixedPricing.Ptr()
+			variationData.PriceMoney = money
+		}
+
+		itemData.Variations = append(itemData.Variations, &square.CatalogObject{
+			Type: "ITEM_VARIATION",
+			ItemVariation: &square.CatalogObjectItemVariation{
+				ID:                "#variation-" + strconv.Itoa(i+1),
+				ItemVariationData: variationData,
+			},
+		})
+	}
+
+	item := &square.CatalogObject{
+		Type: "ITEM",
+		Item: &square.CatalogObjectItem{ID: "#item", ItemData: itemData},
+	}
+
+	upserted, err := upsertCatalogObjects(ctx, append([]*square.CatalogObject{item}, categories.created...))
+	if err != nil {
+		return nil, err
+	}
+
+	b.catalog.apply(upserted)
+	idx = idx.withObjects(upserted)
+
+	variationIDs := make([]string, len(variations))
+	for i, variation := range variations {
+		variationID, ok := idx.variationIDForSKU(variation.SKU)
+		if !ok {
+			return nil, fmt.Errorf("square did not return the variation for sku %s", variation.SKU)
+		}
+		variationIDs[i] = variationID
+
+		audit.Note(ctx, variation.SKU, func(record *models.AuditRecord) {
+			record.VariationID, record.LocationID = variationID, locationID
+		})
+	}
+
+	log.Printf("Created catalog item %s with %d variations", idx.variationDetails[variationIDs[0]].itemID, len(variations))
+
+	now := time.Now()
+	counts := map[string]*locationCount{}
+	for start := 0; start < len(variations); start += maxChangesPerRequest {
+		end := min(start+maxChangesPerRequest, len(variations))
+
+		changes := []*square.InventoryChange{}
+		for i := start; i < end; i++ {
+			changes = append(changes, newPhysicalCountChange(locationID, variationIDs[i], variations[i].CurrentStock, now))
+		}
+
+		chunkCounts, err := submitInventoryChanges(ctx, locationID, changes)
+		if err != nil {
+			log.Printf("ERROR: Failed to record opening stock for new item: %v", err)
+			return nil, fmt.Errorf("item was created but its opening stock was not recorded: %w", err)
+		}
+		for variationID, count := range chunkCounts {
+			counts[variationID] = count
+		}
+	}
+
+	items := make([]models.InventoryItem, len(variations))
+	for i, variationID := range variationIDs {
+		qty, version := variations[i].CurrentStock, stockVersion(nil, variations[i].CurrentStock)
+		if counts[variationID] != nil {
+			qty, version = counts[variationID].inStock()
+		}
+		items[i] = idx.inventoryItem(variationID, locationID, qty)
+		items[i].Version = version
+		audit.Note(ctx, items[i].SKU, func(record *models.AuditRecord) { record.NewStock = &items[i].CurrentStock })
+	}
+
+	return items, nil
+}
+
+// locationCurrency returns the currency the location prices items in.
+func (b *SquareBackend) locationCurrency(ctx context.Context, locationID string) (string, error) {
+	locations, err := b.cachedLocations(ctx, 0)
+	if err != nil {
+		return "", err
+	}
+
+	for _, location := range locations {
+		if location.ID == locationID && location.Currency != "" {
+			return location.Currency, nil
+		}
+	}
+	return defaultCurrency, nil
+}
@@ -0,0 +1,40 @@
+package squareUtils
+
+import (
+	"aoa-inventory/squareUtils/models"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCreateInventoryItem(t *testing.T) {
+	backend, _ := newFakeBackend(t)
+	ctx := context.Background()
+
+	items, err := backend.CreateInventoryItem(ctx, "", &models.NewInventoryItem{
+		Name:     "Canvas Tote",
+		Category: "Merch",
+		Variations: []models.NewVariation{
+			{Name: "Small", SKU: "TOTE-S", CurrentStock: 3},
+			{Name: "Large", SKU: "TOTE-L", CurrentStock: 6},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateInventoryItem: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want 2", len(items))
+	}
+
+	for sku, stock := range map[string]int{"TOTE-S": 3, "TOTE-L": 6} {
+		item := getItem(t, backend, sku, "")
+		if item.Name != "Canvas Tote" || item.Category != "Merch" || item.CurrentStock != stock {
+			t.Errorf("%s = %q in %q with %d, want Canvas Tote in Merch with %d", sku, item.Name, item.Category, item.CurrentStock, stock)
+		}
+	}
+
+	_, err = backend.CreateInventoryItem(ctx, "", &models.NewInventoryItem{Name: "Another Latte", SKU: "LAT-001"})
+	if !errors.Is(err, ErrDuplicateSKU) {
+		t.Errorf("duplicate SKU: err = %v, want ErrDuplicateSKU", err)
+	}
+}
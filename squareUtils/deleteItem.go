@@ -0,0 +1,42 @@
+package squareUtils
+
+import (
+	"aoa-inventory/audit"
+	"aoa-inventory/squareUtils/models"
+	"context"
+
+	square "github.com/square/square-go-sdk"
+)
+
+// DeleteInventoryItem archives the parent item of the SKU, which hides every variation of it. A
+// hard delete removes just the variation, or the whole item when it is the only variation,
+// since Square items need at least one.
+func (b *SquareBackend) DeleteInventoryItem(ctx context.Context, sku string, hard bool) error {
+	idx, variationID, err := b.resolveSKU(ctx, sku)
+	if err != nil {
+		return err
+	}
+
+	audit.Note(ctx, sku, func(record *models.AuditRecord) { record.VariationID = variationID })
+
+	if !hard {
+		_, err := b.updateCatalogItem(ctx, idx, "", variationID, &models.InventoryItemUpdate{Archived: square.Bool(true)})
+		return err
+	}
+
+	objectID := variationID
+	if itemID := idx.variationDetails[variationID].itemID; itemID != "" && idx.variationCount(itemID) <= 1 {
+		objectID = itemID
+	}
+
+	deletedIDs, err := deleteCatalogObject(ctx, objectID)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Deleted %d catalog objects for sku %s", len(deletedIDs), sku)
+
+	// The index has no way to drop objects, so the catalog is reloaded on the next read.
+	b.catalog.Invalidate()
+	return nil
+}
@@ -0,0 +1,89 @@
+package squareUtils
+
+import (
+	"aoa-inventory/squareUtils/models"
+	"context"
+	"errors"
+	"testing"
+)
+
+func listedSKUs(t *testing.T, backend InventoryBackend, query InventoryQuery) map[string]bool {
+	t.Helper()
+
+	inventory, err := backend.ListInventory(context.Background(), query)
+	if err != nil {
+		t.Fatalf("ListInventory: %v", err)
+	}
+
+	skus := map[string]bool{}
+	for _, item := range inventory {
+		skus[item.SKU] = true
+	}
+	return skus
+}
+
+func TestDeleteInventoryItemArchives(t *testing.T) {
+	backend, _ := newFakeBackend(t)
+
+	if err := backend.DeleteInventoryItem(context.Background(), "LAT-001", false); err != nil {
+		t.Fatalf("DeleteInventoryItem: %v", err)
+	}
+
+	if item := getItem(t, backend, "LAT-001", ""); !item.Archived || item.CurrentStock != 10 {
+		t.Errorf("archived item = archived %t with %d, want archived with its stock of 10", item.Archived, item.CurrentStock)
+	}
+	if skus := listedSKUs(t, backend, InventoryQuery{}); skus["LAT-001"] || !skus["BAK-002"] {
+		t.Errorf("listed %v, want only BAK-002", skus)
+	}
+	if skus := listedSKUs(t, backend, InventoryQuery{IncludeArchived: true}); !skus["LAT-001"] {
+		t.Errorf("listed %v with archived items, want LAT-001 among them", skus)
+	}
+
+	updateItem(t, backend, "LAT-001", &models.InventoryItemUpdate{Archived: ptr(false)})
+	if skus := listedSKUs(t, backend, InventoryQuery{}); !skus["LAT-001"] {
+		t.Errorf("listed %v after restoring, want LAT-001 among them", skus)
+	}
+}
+
+func TestDeleteInventoryItemHardDeletesItem(t *testing.T) {
+	backend, _ := newFakeBackend(t)
+	ctx := context.Background()
+
+	if err := backend.DeleteInventoryItem(ctx, "LAT-001", true); err != nil {
+		t.Fatalf("DeleteInventoryItem: %v", err)
+	}
+
+	if _, err := backend.GetInventoryItem(ctx, "LAT-001", InventoryQuery{}); !errors.Is(err, ErrInventoryItemNotFound) {
+		t.Errorf("deleted item: err = %v, want ErrInventoryItemNotFound", err)
+	}
+	if skus := listedSKUs(t, backend, InventoryQuery{IncludeArchived: true}); skus["LAT-001"] || !skus["BAK-002"] {
+		t.Errorf("listed %v, want only BAK-002", skus)
+	}
+}
+
+func TestDeleteInventoryItemHardDeletesOneVariation(t *testing.T) {
+	backend, _ := newFakeBackend(t)
+	ctx := context.Background()
+
+	_, err := backend.CreateInventoryItem(ctx, "", &models.NewInventoryItem{
+		Name: "Canvas Tote",
+		Variations: []models.NewVariation{
+			{Name: "Small", SKU: "TOTE-S", CurrentStock: 3},
+			{Name: "Large", SKU: "TOTE-L", CurrentStock: 6},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateInventoryItem: %v", err)
+	}
+
+	if err := backend.DeleteInventoryItem(ctx, "TOTE-S", true); err != nil {
+		t.Fatalf("DeleteInventoryItem: %v", err)
+	}
+
+	if _, err := backend.GetInventoryItem(ctx, "TOTE-S", InventoryQuery{}); !errors.Is(err, ErrInventoryItemNotFound) {
+		t.Errorf("deleted variation: err = %v, want ErrInventoryItemNotFound", err)
+	}
+	if item := getItem(t, backend, "TOTE-L", ""); item.Name != "Canvas Tote" || item.CurrentStock != 6 {
+		t.Errorf("other variation = %q with %d, want Canvas Tote with 6", item.Name, item.CurrentStock)
+	}
+}
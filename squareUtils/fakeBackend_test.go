@@ -0,0 +1,58 @@
+package squareUtils
+
+import (
+	"aoa-inventory/squareUtils/client"
+	"aoa-inventory/squareUtils/fakeSquare"
+	"aoa-inventory/squareUtils/models"
+	"context"
+	"testing"
+	"time"
+)
+
+// testItems seeds the fake Square server in tests.
+var testItems = []models.InventoryItem{
+	{ID: "VAR_LATTE", Name: "Vanilla Latte", SKU: "LAT-001", CurrentStock: 10, Category: "Drink"},
+	{ID: "VAR_CROISSANT", Name: "Almond Croissant", SKU: "BAK-002", CurrentStock: 4, Category: "Bakery"},
+}
+
+// newFakeBackend points the Square client at a fresh fake server seeded with testItems and
+// returns a backend reading it. Tests using it must not run in parallel, since the client is
+// shared.
+func newFakeBackend(t *testing.T) (*SquareBackend, *fakeSquare.Server) {
+	t.Helper()
+
+	server := fakeSquare.NewServer(testItems, "")
+	client.SquareClient = nil
+	client.Init("test-token", "fake", server.URL, 1000, 1000)
+
+	t.Cleanup(func() {
+		server.Close()
+		client.SquareClient = nil
+	})
+
+	return NewSquareBackend(server.LocationID, NewCatalogCache(time.Minute)), server
+}
+
+func getItem(t *testing.T, backend InventoryBackend, sku, locationID string) *models.InventoryItem {
+	t.Helper()
+
+	item, err := backend.GetInventoryItem(context.Background(), sku, InventoryQuery{LocationID: locationID})
+	if err != nil {
+		t.Fatalf("GetInventoryItem(%s, %q): %v", sku, locationID, err)
+	}
+	return item
+}
+
+func updateItem(t *testing.T, backend InventoryBackend, sku string, update *models.InventoryItemUpdate) *models.InventoryItem {
+	t.Helper()
+
+	item, err := backend.UpdateInventoryItem(context.Background(), "", sku, update)
+	if err != nil {
+		t.Fatalf("UpdateInventoryItem(%s): %v", sku, err)
+	}
+	return item
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
@@ -0,0 +1,100 @@
+package fakeSquare
+
+import (
+	"net/http"
+	"strings"
+
+	square "github.com/square/square-go-sdk"
+)
+
+var catalogObjectTypes = map[string]bool{
+	"ITEM":           true,
+	"ITEM_VARIATION": true,
+	"IMAGE":          true,
+	"CATEGORY":       true,
+	"ITEM_OPTION":    true,
+}
+
+func (s *Server) handleCatalogList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	typeFilter := map[string]bool{}
+	if rawTypes := query.Get("types"); rawTypes != "" {
+		for _, objectType := range strings.Split(rawTypes, ",") {
+			objectType = strings.ToUpper(strings.TrimSpace(objectType))
+			if objectType == "" {
+				continue
+			}
+			if !catalogObjectTypes[objectType] {
+				writeBadRequest(w, square.ErrorCodeInvalidEnumValue, "Invalid catalog object type `"+objectType+"`.", "types")
+				return
+			}
+			typeFilter[objectType] = true
+		}
+	}
+
+	offset, ok := decodeCursor(query.Get("cursor"))
+	if !ok {
+		writeBadRequest(w, square.ErrorCodeInvalidCursor, "The provided cursor is invalid.", "cursor")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	matching := []*square.CatalogObject{}
+	for _, obj := range s.objects {
+		if isDeleted(obj) {
+			continue
+		}
+		if len(typeFilter) > 0 && !typeFilter[obj.GetType()] {
+			continue
+		}
+		matching = append(matching, obj)
+	}
+
+	if offset > len(matching) {
+		writeBadRequest(w, square.ErrorCodeInvalidCursor, "The provided cursor is invalid.", "cursor")
+		return
+	}
+
+	end := min(offset+s.pageSize(), len(matching))
+	resp := &square.ListCatalogResponse{Objects: matching[offset:end]}
+	if end < len(matching) {
+		resp.Cursor = square.String(encodeCursor(end))
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) pageSize() int {
+	if s.PageSize <= 0 {
+		return defaultPageSize
+	}
+	return s.PageSize
+}
+
+// findVariation returns the non-deleted ITEM_VARIATION with the given ID.
+func (s *Server) findVariation(variationID string) *square.CatalogObjectItemVariation {
+	for _, obj := range s.objects {
+		if obj.ItemVariation != nil && obj.ItemVariation.ID == variationID && !isDeleted(obj) {
+			return obj.ItemVariation
+		}
+	}
+	return nil
+}
+
+func isDeleted(obj *square.CatalogObject) bool {
+	var deleted *bool
+	switch {
+	case obj.Item != nil:
+		deleted = obj.Item.IsDeleted
+	case obj.ItemVariation != nil:
+		deleted = obj.ItemVariation.IsDeleted
+	case obj.Image != nil:
+		deleted = obj.Image.IsDeleted
+	case obj.Category != nil:
+		deleted = obj.Category.IsDeleted
+	}
+	return deleted != nil && *deleted
+}
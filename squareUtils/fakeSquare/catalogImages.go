@@ -0,0 +1,110 @@
+package fakeSquare
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	square "github.com/square/square-go-sdk"
+)
+
+// maxImageUpload is the largest image file Square accepts.
+const maxImageUpload = 15 << 20
+
+// handleCreateCatalogImage stores an uploaded image as an IMAGE object with a made-up URL and
+// attaches it to the item or variation named in the request.
+func (s *Server) handleCreateCatalogImage(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(maxImageUpload); err != nil {
+		writeBadRequest(w, square.ErrorCodeBadRequest, "Invalid multipart body: "+err.Error(), "")
+		return
+	}
+
+	var req square.CreateCatalogImageRequest
+	if err := json.Unmarshal([]byte(r.FormValue("request")), &req); err != nil {
+		writeBadRequest(w, square.ErrorCodeBadRequest, "Invalid JSON in `request`: "+err.Error(), "request")
+		return
+	}
+	if req.IdempotencyKey == "" {
+		writeBadRequest(w, square.ErrorCodeMissingRequiredParameter, "Field must be set", "idempotency_key")
+		return
+	}
+	if req.Image == nil || req.Image.Image == nil {
+		writeBadRequest(w, square.ErrorCodeMissingRequiredParameter, "Field must be set", "image")
+		return
+	}
+
+	file, header, err := r.FormFile("image_file")
+	if err != nil {
+		writeBadRequest(w, square.ErrorCodeMissingRequiredParameter, "Field must be set", "image_file")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeBadRequest(w, square.ErrorCodeBadRequest, "Could not read image file.", "image_file")
+		return
+	}
+	if len(data) > maxImageUpload {
+		writeBadRequest(w, square.ErrorCodeInvalidValue, "Image files must be 15 MB or smaller.", "image_file")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The multipart boundary changes between requests, so replays compare the parts instead.
+	body := append([]byte(r.FormValue("request")), data...)
+	if s.replayIdempotent(w, r.URL.Path, req.IdempotencyKey, body) {
+		return
+	}
+
+	var target *square.CatalogObject
+	if req.ObjectID != nil && *req.ObjectID != "" {
+		target = s.findObject(*req.ObjectID)
+		if target == nil || isDeleted(target) || (target.Item == nil && target.ItemVariation == nil) {
+			writeBadRequest(w, square.ErrorCodeInvalidValue, "Object `"+*req.ObjectID+"` was not found.", "object_id")
+			return
+		}
+	}
+
+	now := time.Now().UTC()
+	version := s.nextCatalogVersion()
+	imageID := strings.ToUpper(strings.ReplaceAll(uuid.NewString(), "-", ""))[:24]
+
+	image := req.Image
+	image.Image.ID = imageID
+	if image.Image.ImageData == nil {
+		image.Image.ImageData = &square.CatalogImage{}
+	}
+	image.Image.ImageData.URL = square.String("https://fake-square.invalid/files/" + imageID + "/original" + path.Ext(header.Filename))
+	stampObject(image, version, now.Format(time.RFC3339), false)
+	s.storeObject(image)
+
+	if target != nil {
+		primary := req.IsPrimary != nil && *req.IsPrimary
+		switch {
+		case target.Item != nil && target.Item.ItemData != nil:
+			target.Item.ItemData.ImageIDs = attachImage(target.Item.ItemData.ImageIDs, imageID, primary)
+		case target.ItemVariation != nil && target.ItemVariation.ItemVariationData != nil:
+			target.ItemVariation.ItemVariationData.ImageIDs = attachImage(target.ItemVariation.ItemVariationData.ImageIDs, imageID, primary)
+		}
+		stampObject(target, version, now.Format(time.RFC3339), false)
+	}
+
+	s.respondIdempotent(w, r.URL.Path, req.IdempotencyKey, body, &square.CreateCatalogImageResponse{Image: image})
+}
+
+// attachImage adds an image ID to an object's list. As in Square, a primary image replaces the
+// current first image and any other is appended.
+func attachImage(imageIDs []string, imageID string, primary bool) []string {
+	if primary && len(imageIDs) > 0 {
+		imageIDs[0] = imageID
+		return imageIDs
+	}
+	return append(imageIDs, imageID)
+}
@@ -0,0 +1,124 @@
+package fakeSquare
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	square "github.com/square/square-go-sdk"
+)
+
+// handleSearchCatalog supports the exact query on an attribute such as sku, which like Square's
+// matches ignoring case.
+func (s *Server) handleSearchCatalog(w http.ResponseWriter, r *http.Request) {
+	var req square.SearchCatalogObjectsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeBadRequest(w, square.ErrorCodeBadRequest, "Invalid JSON body: "+err.Error(), "")
+		return
+	}
+
+	if req.Query == nil || req.Query.ExactQuery == nil {
+		writeBadRequest(w, square.ErrorCodeBadRequest, "The fake server only supports exact queries.", "query")
+		return
+	}
+	exact := req.Query.ExactQuery
+
+	typeFilter := map[string]bool{}
+	for _, objectType := range req.ObjectTypes {
+		typeFilter[string(objectType)] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &square.SearchCatalogObjectsResponse{}
+	for _, obj := range s.objects {
+		if isDeleted(obj) || (len(typeFilter) > 0 && !typeFilter[obj.GetType()]) {
+			continue
+		}
+		if value, ok := attributeValue(obj, exact.AttributeName); ok && strings.EqualFold(value, exact.AttributeValue) {
+			resp.Objects = append(resp.Objects, obj)
+		}
+	}
+
+	if req.IncludeRelatedObjects != nil && *req.IncludeRelatedObjects {
+		resp.RelatedObjects = s.relatedObjects(resp.Objects...)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleBatchRetrieveCatalog(w http.ResponseWriter, r *http.Request) {
+	var req square.BatchGetCatalogObjectsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeBadRequest(w, square.ErrorCodeBadRequest, "Invalid JSON body: "+err.Error(), "")
+		return
+	}
+
+	if len(req.ObjectIDs) == 0 {
+		writeBadRequest(w, square.ErrorCodeMissingRequiredParameter, "Field must be set", "object_ids")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &square.BatchGetCatalogObjectsResponse{}
+	for _, id := range req.ObjectIDs {
+		if obj := s.findObject(id); obj != nil && !isDeleted(obj) {
+			resp.Objects = append(resp.Objects, obj)
+		}
+	}
+
+	if req.IncludeRelatedObjects != nil && *req.IncludeRelatedObjects {
+		resp.RelatedObjects = s.relatedObjects(resp.Objects...)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// relatedObjects returns what Square includes as related objects: the parent item of a
+// variation, and the category and images of an item. Like Square's, it goes one level deep.
+func (s *Server) relatedObjects(objects ...*square.CatalogObject) []*square.CatalogObject {
+	related := []*square.CatalogObject{}
+	seen := map[string]bool{}
+	add := func(id string) {
+		if id == "" || seen[id] {
+			return
+		}
+		seen[id] = true
+		if obj := s.findObject(id); obj != nil && !isDeleted(obj) {
+			related = append(related, obj)
+		}
+	}
+
+	for _, obj := range objects {
+		switch {
+		case obj.ItemVariation != nil && obj.ItemVariation.ItemVariationData != nil && obj.ItemVariation.ItemVariationData.ItemID != nil:
+			add(*obj.ItemVariation.ItemVariationData.ItemID)
+		case obj.Item != nil && obj.Item.ItemData != nil:
+			if obj.Item.ItemData.CategoryID != nil {
+				add(*obj.Item.ItemData.CategoryID)
+			}
+			for _, imageID := range obj.Item.ItemData.ImageIDs {
+				add(imageID)
+			}
+		}
+	}
+
+	return related
+}
+
+// attributeValue returns the searchable attribute of the object with the given name.
+func attributeValue(obj *square.CatalogObject, name string) (string, bool) {
+	switch {
+	case name == "sku" && obj.ItemVariation != nil && obj.ItemVariation.ItemVariationData != nil && obj.ItemVariation.ItemVariationData.Sku != nil:
+		return *obj.ItemVariation.ItemVariationData.Sku, true
+	case name == "name" && obj.Item != nil && obj.Item.ItemData != nil && obj.Item.ItemData.Name != nil:
+		return *obj.Item.ItemData.Name, true
+	case name == "name" && obj.ItemVariation != nil && obj.ItemVariation.ItemVariationData != nil && obj.ItemVariation.ItemVariationData.Name != nil:
+		return *obj.ItemVariation.ItemVariationData.Name, true
+	}
+	return "", false
+}
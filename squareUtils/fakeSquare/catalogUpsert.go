@@ -0,0 +1,342 @@
+package fakeSquare
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	square "github.com/square/square-go-sdk"
+)
+
+// errorCodeVersionMismatch is what Square returns for writes with a stale object version.
+const errorCodeVersionMismatch square.ErrorCode = "VERSION_MISMATCH"
+
+func (s *Server) handleRetrieveCatalogObject(w http.ResponseWriter, r *http.Request) {
+	objectID := r.PathValue("object_id")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj := s.findObject(objectID)
+	if obj == nil || isDeleted(obj) {
+		writeError(w, http.StatusNotFound, square.ErrorCategoryInvalidRequestError, square.ErrorCodeNotFound, "Object `"+objectID+"` was not found.", "")
+		return
+	}
+
+	resp := &square.GetCatalogObjectResponse{Object: obj}
+	if includeRelated, _ := strconv.ParseBool(r.URL.Query().Get("include_related_objects")); includeRelated {
+		resp.RelatedObjects = s.relatedObjects(obj)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleBatchUpsertCatalog(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeBadRequest(w, square.ErrorCodeBadRequest, "Could not read request body.", "")
+		return
+	}
+
+	var req square.BatchUpsertCatalogObjectsRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeBadRequest(w, square.ErrorCodeBadRequest, "Invalid JSON body: "+err.Error(), "")
+		return
+	}
+
+	if req.IdempotencyKey == "" {
+		writeBadRequest(w, square.ErrorCodeMissingRequiredParameter, "Field must be set", "idempotency_key")
+		return
+	}
+	if len(req.Batches) == 0 {
+		writeBadRequest(w, square.ErrorCodeMissingRequiredParameter, "Field must be set", "batches")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.replayIdempotent(w, r.URL.Path, req.IdempotencyKey, body) {
+		return
+	}
+
+	// Validate every batch before applying any, so a failed request changes nothing.
+	idMappings := map[string]string{}
+	for i, batch := range req.Batches {
+		if batch == nil || len(batch.Objects) == 0 {
+			writeBadRequest(w, square.ErrorCodeMissingRequiredParameter, "Field must be set", "batches["+strconv.Itoa(i)+"].objects")
+			return
+		}
+		if code, detail, field := s.validateUpsert(batch.Objects, idMappings); code != "" {
+			status := http.StatusBadRequest
+			if code == errorCodeVersionMismatch {
+				status = http.StatusConflict
+			}
+			writeError(w, status, square.ErrorCategoryInvalidRequestError, code, detail, "batches["+strconv.Itoa(i)+"]."+field)
+			return
+		}
+	}
+
+	now := time.Now().UTC()
+	resp := &square.BatchUpsertCatalogObjectsResponse{UpdatedAt: square.String(now.Format(time.RFC3339))}
+	for _, batch := range req.Batches {
+		s.applyUpsert(batch.Objects, idMappings, now)
+		resp.Objects = append(resp.Objects, batch.Objects...)
+	}
+	for clientID, objectID := range idMappings {
+		resp.IDMappings = append(resp.IDMappings, &square.CatalogIDMapping{ClientObjectID: square.String(clientID), ObjectID: square.String(objectID)})
+	}
+
+	s.respondIdempotent(w, r.URL.Path, req.IdempotencyKey, body, resp)
+}
+
+// handleDeleteCatalogObject deletes an object and, for an item, its variations. Inventory counts
+// of deleted variations are dropped so they no longer show up in count reads.
+func (s *Server) handleDeleteCatalogObject(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("object_id")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj := s.findObject(id)
+	if obj == nil || isDeleted(obj) {
+		writeError(w, http.StatusNotFound, square.ErrorCategoryInvalidRequestError, square.ErrorCodeNotFound, "Object `"+id+"` was not found.", "")
+		return
+	}
+
+	deleted := []*square.CatalogObject{obj}
+	if obj.Item != nil {
+		for _, existing := range s.objects {
+			variation := existing.ItemVariation
+			if variation != nil && !isDeleted(existing) && variation.ItemVariationData != nil &&
+				variation.ItemVariationData.ItemID != nil && *variation.ItemVariationData.ItemID == id {
+				deleted = append(deleted, existing)
+			}
+		}
+	}
+
+	now := time.Now().UTC()
+	version := s.nextCatalogVersion()
+	resp := &square.DeleteCatalogObjectResponse{DeletedAt: square.String(now.Format(time.RFC3339))}
+	for _, obj := range deleted {
+		deletedID := objectID(obj)
+		stampObject(obj, version, now.Format(time.RFC3339), true)
+		resp.DeletedObjectIDs = append(resp.DeletedObjectIDs, deletedID)
+
+		for key := range s.counts {
+			if key.catalogObjectID == deletedID {
+				delete(s.counts, key)
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// withVariations returns the objects followed by the variations nested in any items.
+func withVariations(objects []*square.CatalogObject) []*square.CatalogObject {
+	all := []*square.CatalogObject{}
+	for _, obj := range objects {
+		all = append(all, obj)
+		if obj != nil && obj.Item != nil && obj.Item.ItemData != nil {
+			all = append(all, obj.Item.ItemData.Variations...)
+		}
+	}
+	return all
+}
+
+// validateUpsert checks the objects of one batch and assigns permanent IDs to the temporary
+// "#" IDs in it. It returns a Square error code, detail and field when the batch is invalid.
+func (s *Server) validateUpsert(objects []*square.CatalogObject, idMappings map[string]string) (square.ErrorCode, string, string) {
+	for i, obj := range withVariations(objects) {
+		field := "objects[" + strconv.Itoa(i) + "]"
+		if obj == nil || !catalogObjectTypes[obj.GetType()] {
+			return square.ErrorCodeInvalidValue, "Unsupported catalog object type.", field + ".type"
+		}
+
+		id := objectID(obj)
+		if id == "" {
+			return square.ErrorCodeMissingRequiredParameter, "Field must be set", field + ".id"
+		}
+
+		if strings.HasPrefix(id, "#") {
+			if _, ok := idMappings[id]; ok {
+				return square.ErrorCodeInvalidValue, "Duplicate object ID `" + id + "`.", field + ".id"
+			}
+			idMappings[id] = strings.ToUpper(strings.ReplaceAll(uuid.NewString(), "-", ""))[:24]
+			continue
+		}
+
+		existing := s.findObject(id)
+		if existing == nil || isDeleted(existing) {
+			return square.ErrorCodeInvalidValue, "Object `" + id + "` was not found.", field + ".id"
+		}
+		if existing.GetType() != obj.GetType() {
+			return square.ErrorCodeInvalidValue, "Object `" + id + "` is a " + existing.GetType() + ".", field + ".type"
+		}
+		if version := objectVersion(obj); version != nil && *version != *objectVersion(existing) {
+			return errorCodeVersionMismatch, "Object version does not match for object: " + id, field + ".version"
+		}
+	}
+
+	return "", "", ""
+}
+
+// applyUpsert stores a validated batch, resolving temporary IDs and bumping versions. As in
+// Square, variations left out of an upserted item are deleted.
+func (s *Server) applyUpsert(objects []*square.CatalogObject, idMappings map[string]string, now time.Time) {
+	resolve := func(id *string) {
+		if id == nil {
+			return
+		}
+		if mapped, ok := idMappings[*id]; ok {
+			*id = mapped
+		}
+	}
+
+	version := s.nextCatalogVersion()
+	updatedAt := now.Format(time.RFC3339)
+
+	for _, obj := range withVariations(objects) {
+		id := objectID(obj)
+		resolve(&id)
+		setObjectID(obj, id)
+
+		switch {
+		case obj.Item != nil && obj.Item.ItemData != nil:
+			itemData := obj.Item.ItemData
+			resolve(itemData.CategoryID)
+			for _, category := range itemData.Categories {
+				if category != nil {
+					resolve(category.ID)
+				}
+			}
+			if itemData.ReportingCategory != nil {
+				resolve(itemData.ReportingCategory.ID)
+			}
+			for i := range itemData.ImageIDs {
+				resolve(&itemData.ImageIDs[i])
+			}
+		case obj.ItemVariation != nil && obj.ItemVariation.ItemVariationData != nil:
+			resolve(obj.ItemVariation.ItemVariationData.ItemID)
+		}
+
+		stampObject(obj, version, updatedAt, false)
+		s.storeObject(obj)
+	}
+
+	for _, obj := range objects {
+		if obj.Item == nil {
+			continue
+		}
+
+		kept := map[string]bool{}
+		if obj.Item.ItemData != nil {
+			for _, variation := range obj.Item.ItemData.Variations {
+				kept[objectID(variation)] = true
+			}
+		}
+
+		for _, existing := range s.objects {
+			variation := existing.ItemVariation
+			if variation == nil || kept[variation.ID] || isDeleted(existing) || variation.ItemVariationData == nil {
+				continue
+			}
+			if itemID := variation.ItemVariationData.ItemID; itemID != nil && *itemID == obj.Item.ID {
+				stampObject(existing, version, updatedAt, true)
+			}
+		}
+	}
+}
+
+// storeObject replaces the stored object with the same ID or adds a new one.
+func (s *Server) storeObject(obj *square.CatalogObject) {
+	id := objectID(obj)
+	for i, existing := range s.objects {
+		if objectID(existing) == id {
+			s.objects[i] = obj
+			return
+		}
+	}
+	s.objects = append(s.objects, obj)
+}
+
+// findObject returns the stored object with the given ID, deleted or not.
+func (s *Server) findObject(id string) *square.CatalogObject {
+	for _, obj := range s.objects {
+		if objectID(obj) == id {
+			return obj
+		}
+	}
+	return nil
+}
+
+// nextCatalogVersion returns a version newer than any handed out so far.
+func (s *Server) nextCatalogVersion() int64 {
+	version := time.Now().UnixMilli()
+	if version <= s.catalogVersion {
+		version = s.catalogVersion + 1
+	}
+	s.catalogVersion = version
+	return version
+}
+
+func objectID(obj *square.CatalogObject) string {
+	switch {
+	case obj == nil:
+		return ""
+	case obj.Item != nil:
+		return obj.Item.ID
+	case obj.ItemVariation != nil:
+		return obj.ItemVariation.ID
+	case obj.Image != nil:
+		return obj.Image.ID
+	case obj.Category != nil && obj.Category.ID != nil:
+		return *obj.Category.ID
+	}
+	return ""
+}
+
+func setObjectID(obj *square.CatalogObject, id string) {
+	switch {
+	case obj.Item != nil:
+		obj.Item.ID = id
+	case obj.ItemVariation != nil:
+		obj.ItemVariation.ID = id
+	case obj.Image != nil:
+		obj.Image.ID = id
+	case obj.Category != nil:
+		obj.Category.ID = square.String(id)
+	}
+}
+
+func objectVersion(obj *square.CatalogObject) *int64 {
+	switch {
+	case obj.Item != nil:
+		return obj.Item.Version
+	case obj.ItemVariation != nil:
+		return obj.ItemVariation.Version
+	case obj.Image != nil:
+		return obj.Image.Version
+	case obj.Category != nil:
+		return obj.Category.Version
+	}
+	return nil
+}
+
+func stampObject(obj *square.CatalogObject, version int64, updatedAt string, deleted bool) {
+	switch {
+	case obj.Item != nil:
+		obj.Item.Version, obj.Item.UpdatedAt, obj.Item.IsDeleted = square.Int64(version), square.String(updatedAt), square.Bool(deleted)
+	case obj.ItemVariation != nil:
+		obj.ItemVariation.Version, obj.ItemVariation.UpdatedAt, obj.ItemVariation.IsDeleted = square.Int64(version), square.String(updatedAt), square.Bool(deleted)
+	case obj.Image != nil:
+		obj.Image.Version, obj.Image.UpdatedAt, obj.Image.IsDeleted = square.Int64(version), square.String(updatedAt), square.Bool(deleted)
+	case obj.Category != nil:
+		obj.Category.Version, obj.Category.UpdatedAt, obj.Category.IsDeleted = square.Int64(version), square.String(updatedAt), square.Bool(deleted)
+	}
+}
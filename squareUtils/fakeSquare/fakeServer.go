@@ -0,0 +1,236 @@
+// Package fakeSquare implements an in-process stand-in for the parts of the Square API
+// this service uses, so it can be run and exercised without network access or credentials.
+package fakeSquare
+
+import (
+	"aoa-inventory/squareUtils/models"
+	"aoa-inventory/utils"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	square "github.com/square/square-go-sdk"
+)
+
+var log = utils.NewLogger("FAKE-SQUARE")
+
+const (
+	DefaultLocationID = "FAKE_LOCATION"
+	defaultPageSize   = 100
+)
+
+// Server is an httptest server that serves a fake Square catalog and inventory.
+type Server struct {
+	*httptest.Server
+
+	// AccessToken, when set, is the only bearer token the server accepts.
+	AccessToken string
+
+	// LocationID is the location the fixture stock was seeded at.
+	LocationID string
+
+	// PageSize is the maximum number of objects returned per catalog page.
+	PageSize int
+
+	mu             sync.Mutex
+	locations      []*square.Location
+	objects        []*square.CatalogObject
+	catalogVersion int64
+	counts         map[countKey]*countEntry
+	changes        []*square.InventoryChange
+	idempotency    map[string]idempotentResponse
+	failures       map[string][]injectedFailure
+}
+
+type injectedFailure struct {
+	status int
+	err    *square.Error
+}
+
+type idempotentResponse struct {
+	requestBody  string
+	responseBody []byte
+}
+
+// NewServer starts a fake Square server seeded with the given items at locationID.
+func NewServer(items []models.InventoryItem, locationID string) *Server {
+	if locationID == "" {
+		locationID = DefaultLocationID
+	}
+
+	s := &Server{
+		LocationID:  locationID,
+		PageSize:    defaultPageSize,
+		counts:      map[countKey]*countEntry{},
+		idempotency: map[string]idempotentResponse{},
+		failures:    map[string][]injectedFailure{},
+	}
+	s.AddLocation(locationID, "Fake Location")
+	s.seed(items)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v2/locations", s.handleListLocations)
+	mux.HandleFunc("GET /v2/catalog/list", s.handleCatalogList)
+	mux.HandleFunc("GET /v2/catalog/object/{object_id}", s.handleRetrieveCatalogObject)
+	mux.HandleFunc("DELETE /v2/catalog/object/{object_id}", s.handleDeleteCatalogObject)
+	mux.HandleFunc("POST /v2/catalog/images", s.handleCreateCatalogImage)
+	mux.HandleFunc("POST /v2/catalog/batch-upsert", s.handleBatchUpsertCatalog)
+	mux.HandleFunc("POST /v2/catalog/batch-retrieve", s.handleBatchRetrieveCatalog)
+	mux.HandleFunc("POST /v2/catalog/search", s.handleSearchCatalog)
+	mux.HandleFunc("POST /v2/inventory/counts/batch-retrieve", s.handleBatchGetCounts)
+	mux.HandleFunc("POST /v2/inventory/changes/batch-create", s.handleBatchCreateChanges)
+	mux.HandleFunc("POST /v2/inventory/changes/batch-retrieve", s.handleBatchGetChanges)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, square.ErrorCategoryInvalidRequestError, square.ErrorCodeNotFound, "API endpoint for URL path `"+r.URL.Path+"` and HTTP method `"+r.Method+"` is not found.", "")
+	})
+
+	s.Server = httptest.NewServer(s.middleware(mux))
+	log.Printf("Fake Square server listening at %s", s.URL)
+
+	return s
+}
+
+// FailNext makes the next request to path fail with the given status and Square error code.
+func (s *Server) FailNext(path string, status int, code square.ErrorCode, detail string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	category := square.ErrorCategoryInvalidRequestError
+	switch {
+	case status == http.StatusUnauthorized:
+		category = square.ErrorCategoryAuthenticationError
+	case status == http.StatusTooManyRequests:
+		category = square.ErrorCategoryRateLimitError
+	case status >= http.StatusInternalServerError:
+		category = square.ErrorCategoryAPIError
+	}
+
+	s.failures[path] = append(s.failures[path], injectedFailure{
+		status: status,
+		err:    &square.Error{Category: category, Code: code, Detail: square.String(detail)},
+	})
+}
+
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" || (s.AccessToken != "" && token != s.AccessToken) {
+			writeError(w, http.StatusUnauthorized, square.ErrorCategoryAuthenticationError, square.ErrorCodeUnauthorized, "This request could not be authorized.", "")
+			return
+		}
+
+		if failure, ok := s.popFailure(r.URL.Path); ok {
+			writeJSON(w, failure.status, map[string]any{"errors": []*square.Error{failure.err}})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) popFailure(path string) (injectedFailure, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queued := s.failures[path]
+	if len(queued) == 0 {
+		return injectedFailure{}, false
+	}
+
+	s.failures[path] = queued[1:]
+	return queued[0], true
+}
+
+// replayIdempotent answers a request whose idempotency key was already used on path, either
+// with the stored response or, if the body differs, with IDEMPOTENCY_KEY_REUSED. The caller
+// must hold s.mu.
+func (s *Server) replayIdempotent(w http.ResponseWriter, path, key string, body []byte) bool {
+	previous, ok := s.idempotency[path+" "+key]
+	if !ok {
+		return false
+	}
+
+	if previous.requestBody != string(body) {
+		writeBadRequest(w, square.ErrorCodeIdempotencyKeyReused, "The idempotency key was already used with a different request.", "idempotency_key")
+		return true
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(previous.responseBody)
+	return true
+}
+
+// respondIdempotent writes resp and stores it for replays of the same key. The caller must
+// hold s.mu.
+func (s *Server) respondIdempotent(w http.ResponseWriter, path, key string, body []byte, resp any) {
+	respBody, err := json.Marshal(resp)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, square.ErrorCategoryAPIError, square.ErrorCodeInternalServerError, err.Error(), "")
+		return
+	}
+
+	s.idempotency[path+" "+key] = idempotentResponse{requestBody: string(body), responseBody: respBody}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(respBody)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		log.Printf("ERROR: Could not encode response: %v", err)
+		status = http.StatusInternalServerError
+		data = []byte(`{"errors":[{"category":"API_ERROR","code":"INTERNAL_SERVER_ERROR"}]}`)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+}
+
+func writeError(w http.ResponseWriter, status int, category square.ErrorCategory, code square.ErrorCode, detail, field string) {
+	sqErr := &square.Error{Category: category, Code: code, Detail: square.String(detail)}
+	if field != "" {
+		sqErr.Field = square.String(field)
+	}
+
+	writeJSON(w, status, map[string]any{"errors": []*square.Error{sqErr}})
+}
+
+func writeBadRequest(w http.ResponseWriter, code square.ErrorCode, detail, field string) {
+	writeError(w, http.StatusBadRequest, square.ErrorCategoryInvalidRequestError, code, detail, field)
+}
+
+// encodeCursor and decodeCursor turn list offsets into opaque cursor strings.
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, bool) {
+	if cursor == "" {
+		return 0, true
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, false
+	}
+
+	offsetStr, ok := strings.CutPrefix(string(raw), "offset:")
+	if !ok {
+		return 0, false
+	}
+
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil || offset < 0 {
+		return 0, false
+	}
+
+	return offset, true
+}
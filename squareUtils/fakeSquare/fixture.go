@@ -0,0 +1,125 @@
+package fakeSquare
+
+import (
+	"aoa-inventory/squareUtils/models"
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	square "github.com/square/square-go-sdk"
+)
+
+// LoadFixture reads seed items from a JSON file in the same format as data.json.
+func LoadFixture(path string) ([]models.InventoryItem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []models.InventoryItem{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// seed converts fixture items into catalog objects and IN_STOCK counts. Each fixture item
+// becomes an ITEM with a single ITEM_VARIATION whose ID is the fixture ID.
+func (s *Server) seed(items []models.InventoryItem) {
+	seededAt := time.Now().UTC()
+	now := seededAt.Format(time.RFC3339)
+	version := s.nextCatalogVersion()
+	categoryIDs := map[string]string{}
+
+	for i, fixtureItem := range items {
+		variationID := fixtureItem.ID
+		if variationID == "" {
+			variationID = "VAR_" + strconv.Itoa(i+1)
+		}
+		itemID := "ITEM_" + variationID
+
+		itemData := &square.CatalogItem{
+			Name:        square.String(fixtureItem.Name),
+			Description: square.String(fixtureItem.Description),
+		}
+
+		if fixtureItem.Category != "" {
+			categoryID, ok := categoryIDs[fixtureItem.Category]
+			if !ok {
+				categoryID = "CAT_" + strings.ToUpper(strings.ReplaceAll(fixtureItem.Category, " ", "_"))
+				categoryIDs[fixtureItem.Category] = categoryID
+				s.objects = append(s.objects, &square.CatalogObject{
+					Type: "CATEGORY",
+					Category: &square.CatalogObjectCategory{
+						ID:           square.String(categoryID),
+						UpdatedAt:    square.String(now),
+						Version:      square.Int64(version),
+						IsDeleted:    square.Bool(false),
+						CategoryData: &square.CatalogCategory{Name: square.String(fixtureItem.Category)},
+					},
+				})
+			}
+			itemData.CategoryID = square.String(categoryID)
+		}
+
+		if fixtureItem.ImageURL != "" {
+			imageID := "IMG_" + variationID
+			itemData.ImageIDs = []string{imageID}
+			s.objects = append(s.objects, &square.CatalogObject{
+				Type: "IMAGE",
+				Image: &square.CatalogObjectImage{
+					ID:        imageID,
+					UpdatedAt: square.String(now),
+					Version:   square.Int64(version),
+					IsDeleted: square.Bool(false),
+					ImageData: &square.CatalogImage{
+						Name: square.String(fixtureItem.Name),
+						URL:  square.String(fixtureItem.ImageURL),
+					},
+				},
+			})
+		}
+
+		sku := fixtureItem.SKU
+		if sku == "" {
+			sku = variationID
+		}
+
+		variation := &square.CatalogObject{
+			Type: "ITEM_VARIATION",
+			ItemVariation: &square.CatalogObjectItemVariation{
+				ID:                    variationID,
+				UpdatedAt:             square.String(now),
+				Version:               square.Int64(version),
+				IsDeleted:             square.Bool(false),
+				PresentAtAllLocations: square.Bool(true),
+				ItemVariationData: &square.CatalogItemVariation{
+					ItemID:         square.String(itemID),
+					Name:           square.String("Regular"),
+					Sku:            square.String(sku),
+					TrackInventory: square.Bool(true),
+				},
+			},
+		}
+		itemData.Variations = []*square.CatalogObject{variation}
+
+		s.objects = append(s.objects, &square.CatalogObject{
+			Type: "ITEM",
+			Item: &square.CatalogObjectItem{
+				ID:                    itemID,
+				UpdatedAt:             square.String(now),
+				Version:               square.Int64(version),
+				IsDeleted:             square.Bool(false),
+				PresentAtAllLocations: square.Bool(true),
+				ItemData:              itemData,
+			},
+		}, variation)
+
+		s.setCount(variationID, s.LocationID, square.InventoryStateInStock, float64(fixtureItem.CurrentStock), seededAt)
+	}
+
+	log.Printf("Seeded %d catalog objects from %d fixture items", len(s.objects), len(items))
+}
@@ -0,0 +1,352 @@
+package fakeSquare
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	square "github.com/square/square-go-sdk"
+)
+
+const (
+	defaultCountsLimit = 100
+	maxCountsLimit     = 1000
+	maxChangesPerBatch = 100
+)
+
+type countKey struct {
+	catalogObjectID string
+	locationID      string
+	state           square.InventoryState
+}
+
+type countEntry struct {
+	quantity     float64
+	calculatedAt time.Time
+}
+
+// untrackedStates are never reported as counts, matching Square's behaviour.
+var untrackedStates = map[square.InventoryState]bool{
+	square.InventoryStateNone:           true,
+	square.InventoryStateSold:           true,
+	square.InventoryStateUnlinkedReturn: true,
+}
+
+type stateTransition struct {
+	from square.InventoryState
+	to   square.InventoryState
+}
+
+// adjustmentTransitions are the adjustments Square documents; it rejects any other pair of states.
+var adjustmentTransitions = map[stateTransition]bool{
+	{square.InventoryStateNone, square.InventoryStateInStock}:           true,
+	{square.InventoryStateInStock, square.InventoryStateSold}:           true,
+	{square.InventoryStateInStock, square.InventoryStateWaste}:          true,
+	{square.InventoryStateUnlinkedReturn, square.InventoryStateInStock}: true,
+	{square.InventoryStateUnlinkedReturn, square.InventoryStateWaste}:   true,
+}
+
+func (s *Server) setCount(catalogObjectID, locationID string, state square.InventoryState, quantity float64, calculatedAt time.Time) {
+	if untrackedStates[state] {
+		return
+	}
+
+	s.counts[countKey{catalogObjectID, locationID, state}] = &countEntry{quantity: quantity, calculatedAt: calculatedAt}
+}
+
+func (s *Server) addCount(catalogObjectID, locationID string, state square.InventoryState, delta float64, calculatedAt time.Time) {
+	current := 0.0
+	if entry, ok := s.counts[countKey{catalogObjectID, locationID, state}]; ok {
+		current = entry.quantity
+	}
+
+	s.setCount(catalogObjectID, locationID, state, current+delta, calculatedAt)
+}
+
+func toInventoryCount(key countKey, entry *countEntry) *square.InventoryCount {
+	state := key.state
+	return &square.InventoryCount{
+		CatalogObjectID:   square.String(key.catalogObjectID),
+		CatalogObjectType: square.String("ITEM_VARIATION"),
+		State:             &state,
+		LocationID:        square.String(key.locationID),
+		Quantity:          square.String(strconv.FormatFloat(entry.quantity, 'f', -1, 64)),
+		CalculatedAt:      square.String(entry.calculatedAt.Format(time.RFC3339Nano)),
+		IsEstimated:       square.Bool(false),
+	}
+}
+
+func (s *Server) handleBatchGetCounts(w http.ResponseWriter, r *http.Request) {
+	var req square.BatchGetInventoryCountsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeBadRequest(w, square.ErrorCodeBadRequest, "Invalid JSON body: "+err.Error(), "")
+		return
+	}
+
+	limit := defaultCountsLimit
+	if req.Limit != nil {
+		limit = *req.Limit
+		if limit < 1 {
+			writeBadRequest(w, square.ErrorCodeValueTooLow, "`limit` must be at least 1.", "limit")
+			return
+		}
+		if limit > maxCountsLimit {
+			writeBadRequest(w, square.ErrorCodeValueTooHigh, "`limit` must be at most 1000.", "limit")
+			return
+		}
+	}
+
+	cursor := ""
+	if req.Cursor != nil {
+		cursor = *req.Cursor
+	}
+
+	offset, ok := decodeCursor(cursor)
+	if !ok {
+		writeBadRequest(w, square.ErrorCodeInvalidCursor, "The provided cursor is invalid.", "cursor")
+		return
+	}
+
+	var updatedAfter time.Time
+	if req.UpdatedAfter != nil {
+		parsed, err := time.Parse(time.RFC3339, *req.UpdatedAfter)
+		if err != nil {
+			writeBadRequest(w, square.ErrorCodeInvalidValue, "`updated_after` must be an RFC 3339 timestamp.", "updated_after")
+			return
+		}
+		updatedAfter = parsed
+	}
+
+	objectFilter := toSet(req.CatalogObjectIDs)
+	locationFilter := toSet(req.LocationIDs)
+	stateFilter := map[square.InventoryState]bool{}
+	for _, state := range req.States {
+		stateFilter[state] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := []countKey{}
+	for key, entry := range s.counts {
+		if len(objectFilter) > 0 && !objectFilter[key.catalogObjectID] {
+			continue
+		}
+		if len(locationFilter) > 0 && !locationFilter[key.locationID] {
+			continue
+		}
+		if len(stateFilter) > 0 && !stateFilter[key.state] {
+			continue
+		}
+		if !updatedAfter.IsZero() && !entry.calculatedAt.After(updatedAfter) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	// Square returns the newest counts first; break ties so cursors stay stable.
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := s.counts[keys[i]].calculatedAt, s.counts[keys[j]].calculatedAt
+		if !a.Equal(b) {
+			return a.After(b)
+		}
+		if keys[i].catalogObjectID != keys[j].catalogObjectID {
+			return keys[i].catalogObjectID < keys[j].catalogObjectID
+		}
+		if keys[i].locationID != keys[j].locationID {
+			return keys[i].locationID < keys[j].locationID
+		}
+		return keys[i].state < keys[j].state
+	})
+
+	if offset > len(keys) {
+		writeBadRequest(w, square.ErrorCodeInvalidCursor, "The provided cursor is invalid.", "cursor")
+		return
+	}
+
+	end := min(offset+limit, len(keys))
+	counts := []*square.InventoryCount{}
+	for _, key := range keys[offset:end] {
+		counts = append(counts, toInventoryCount(key, s.counts[key]))
+	}
+
+	resp := &square.BatchGetInventoryCountsResponse{Counts: counts}
+	if end < len(keys) {
+		resp.Cursor = square.String(encodeCursor(end))
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleBatchCreateChanges(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeBadRequest(w, square.ErrorCodeBadRequest, "Could not read request body.", "")
+		return
+	}
+
+	var req square.BatchChangeInventoryRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeBadRequest(w, square.ErrorCodeBadRequest, "Invalid JSON body: "+err.Error(), "")
+		return
+	}
+
+	if req.IdempotencyKey == "" {
+		writeBadRequest(w, square.ErrorCodeMissingRequiredParameter, "Field must be set", "idempotency_key")
+		return
+	}
+	if len(req.Changes) == 0 {
+		writeBadRequest(w, square.ErrorCodeMissingRequiredParameter, "Field must be set", "changes")
+		return
+	}
+	if len(req.Changes) > maxChangesPerBatch {
+		writeBadRequest(w, square.ErrorCodeValueTooHigh, "At most 100 changes may be sent per request.", "changes")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.replayIdempotent(w, r.URL.Path, req.IdempotencyKey, body) {
+		return
+	}
+
+	// Validate every change before applying any so a bad batch leaves counts untouched.
+	for i, change := range req.Changes {
+		if code, detail, field := s.validateChange(change); code != "" {
+			writeBadRequest(w, code, detail, "changes["+strconv.Itoa(i)+"]."+field)
+			return
+		}
+	}
+
+	now := time.Now().UTC()
+	touched := map[countKey]bool{}
+	for _, change := range req.Changes {
+		s.applyChange(change, now, touched)
+		s.changes = append(s.changes, change)
+	}
+
+	resp := &square.BatchChangeInventoryResponse{
+		Counts:  s.countsFor(touched),
+		Changes: req.Changes,
+	}
+
+	s.respondIdempotent(w, r.URL.Path, req.IdempotencyKey, body, resp)
+}
+
+// validateChange returns a Square error code, detail and field when the change is invalid.
+func (s *Server) validateChange(change *square.InventoryChange) (square.ErrorCode, string, string) {
+	if change == nil || change.Type == nil {
+		return square.ErrorCodeMissingRequiredParameter, "Field must be set", "type"
+	}
+
+	var catalogObjectID, locationID, quantity, occurredAt *string
+	switch *change.Type {
+	case square.InventoryChangeTypeAdjustment:
+		adjustment := change.Adjustment
+		if adjustment == nil {
+			return square.ErrorCodeMissingRequiredParameter, "Field must be set", "adjustment"
+		}
+		if adjustment.FromState == nil {
+			return square.ErrorCodeMissingRequiredParameter, "Field must be set", "adjustment.from_state"
+		}
+		if adjustment.ToState == nil {
+			return square.ErrorCodeMissingRequiredParameter, "Field must be set", "adjustment.to_state"
+		}
+		if !adjustmentTransitions[stateTransition{*adjustment.FromState, *adjustment.ToState}] {
+			detail := "Adjustments from `" + string(*adjustment.FromState) + "` to `" + string(*adjustment.ToState) + "` are not supported."
+			return square.ErrorCodeInvalidValue, detail, "adjustment.to_state"
+		}
+		catalogObjectID, locationID, quantity, occurredAt = adjustment.CatalogObjectID, adjustment.LocationID, adjustment.Quantity, adjustment.OccurredAt
+	case square.InventoryChangeTypePhysicalCount:
+		physicalCount := change.PhysicalCount
+		if physicalCount == nil {
+			return square.ErrorCodeMissingRequiredParameter, "Field must be set", "physical_count"
+		}
+		if physicalCount.State == nil {
+			return square.ErrorCodeMissingRequiredParameter, "Field must be set", "physical_count.state"
+		}
+		if *physicalCount.State != square.InventoryStateInStock {
+			return square.ErrorCodeInvalidValue, "Physical counts must use the IN_STOCK state.", "physical_count.state"
+		}
+		catalogObjectID, locationID, quantity, occurredAt = physicalCount.CatalogObjectID, physicalCount.LocationID, physicalCount.Quantity, physicalCount.OccurredAt
+	default:
+		return square.ErrorCodeInvalidValue, "Change type `" + string(*change.Type) + "` is not supported by the fake Square server.", "type"
+	}
+
+	if catalogObjectID == nil || *catalogObjectID == "" {
+		return square.ErrorCodeMissingRequiredParameter, "Field must be set", "catalog_object_id"
+	}
+	if s.findVariation(*catalogObjectID) == nil {
+		return square.ErrorCodeInvalidValue, "Catalog object `" + *catalogObjectID + "` was not found.", "catalog_object_id"
+	}
+	if locationID == nil || *locationID == "" {
+		return square.ErrorCodeMissingRequiredParameter, "Field must be set", "location_id"
+	}
+	if !s.knownLocation(*locationID) {
+		return square.ErrorCodeInvalidValue, "Location `" + *locationID + "` was not found.", "location_id"
+	}
+	if quantity == nil {
+		return square.ErrorCodeMissingRequiredParameter, "Field must be set", "quantity"
+	}
+	if qty, err := strconv.ParseFloat(*quantity, 64); err != nil || qty < 0 {
+		return square.ErrorCodeInvalidValue, "`quantity` must be a non-negative decimal string.", "quantity"
+	}
+	if occurredAt == nil || *occurredAt == "" {
+		return square.ErrorCodeMissingRequiredParameter, "Field must be set", "occurred_at"
+	}
+	if occurred, err := time.Parse(time.RFC3339, *occurredAt); err != nil {
+		return square.ErrorCodeInvalidValue, "`occurred_at` must be an RFC 3339 timestamp.", "occurred_at"
+	} else if occurred.After(time.Now()) {
+		return square.ErrorCodeInvalidValue, "`occurred_at` cannot be in the future.", "occurred_at"
+	}
+
+	return "", "", ""
+}
+
+func (s *Server) applyChange(change *square.InventoryChange, now time.Time, touched map[countKey]bool) {
+	switch *change.Type {
+	case square.InventoryChangeTypeAdjustment:
+		adjustment := change.Adjustment
+		adjustment.ID = square.String(uuid.NewString())
+		adjustment.CreatedAt = square.String(now.Format(time.RFC3339Nano))
+		adjustment.CatalogObjectType = square.String("ITEM_VARIATION")
+
+		qty, _ := strconv.ParseFloat(*adjustment.Quantity, 64)
+		s.addCount(*adjustment.CatalogObjectID, *adjustment.LocationID, *adjustment.FromState, -qty, now)
+		s.addCount(*adjustment.CatalogObjectID, *adjustment.LocationID, *adjustment.ToState, qty, now)
+		touched[countKey{*adjustment.CatalogObjectID, *adjustment.LocationID, *adjustment.FromState}] = true
+		touched[countKey{*adjustment.CatalogObjectID, *adjustment.LocationID, *adjustment.ToState}] = true
+	case square.InventoryChangeTypePhysicalCount:
+		physicalCount := change.PhysicalCount
+		physicalCount.ID = square.String(uuid.NewString())
+		physicalCount.CreatedAt = square.String(now.Format(time.RFC3339Nano))
+		physicalCount.CatalogObjectType = square.String("ITEM_VARIATION")
+
+		qty, _ := strconv.ParseFloat(*physicalCount.Quantity, 64)
+		s.setCount(*physicalCount.CatalogObjectID, *physicalCount.LocationID, *physicalCount.State, qty, now)
+		touched[countKey{*physicalCount.CatalogObjectID, *physicalCount.LocationID, *physicalCount.State}] = true
+	}
+}
+
+func (s *Server) countsFor(keys map[countKey]bool) []*square.InventoryCount {
+	counts := []*square.InventoryCount{}
+	for key := range keys {
+		if entry, ok := s.counts[key]; ok {
+			counts = append(counts, toInventoryCount(key, entry))
+		}
+	}
+	return counts
+}
+
+func toSet(values []string) map[string]bool {
+	set := map[string]bool{}
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
@@ -0,0 +1,168 @@
+package fakeSquare
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	square "github.com/square/square-go-sdk"
+)
+
+// changeFields returns the fields the changes filter looks at. Physical counts report their
+// state as the state of the change.
+func changeFields(change *square.InventoryChange) (catalogObjectID, locationID string, states []square.InventoryState, occurredAt, createdAt string) {
+	deref := func(value *string) string {
+		if value == nil {
+			return ""
+		}
+		return *value
+	}
+
+	switch {
+	case change.Adjustment != nil:
+		adjustment := change.Adjustment
+		if adjustment.FromState != nil && adjustment.ToState != nil {
+			states = []square.InventoryState{*adjustment.FromState, *adjustment.ToState}
+		}
+		return deref(adjustment.CatalogObjectID), deref(adjustment.LocationID), states, deref(adjustment.OccurredAt), deref(adjustment.CreatedAt)
+	case change.PhysicalCount != nil:
+		physicalCount := change.PhysicalCount
+		if physicalCount.State != nil {
+			states = []square.InventoryState{*physicalCount.State}
+		}
+		return deref(physicalCount.CatalogObjectID), deref(physicalCount.LocationID), states, deref(physicalCount.OccurredAt), deref(physicalCount.CreatedAt)
+	}
+	return "", "", nil, "", ""
+}
+
+func (s *Server) handleBatchGetChanges(w http.ResponseWriter, r *http.Request) {
+	var req square.BatchRetrieveInventoryChangesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeBadRequest(w, square.ErrorCodeBadRequest, "Invalid JSON body: "+err.Error(), "")
+		return
+	}
+
+	limit := defaultCountsLimit
+	if req.Limit != nil {
+		limit = *req.Limit
+		if limit < 1 {
+			writeBadRequest(w, square.ErrorCodeValueTooLow, "`limit` must be at least 1.", "limit")
+			return
+		}
+		if limit > maxCountsLimit {
+			writeBadRequest(w, square.ErrorCodeValueTooHigh, "`limit` must be at most 1000.", "limit")
+			return
+		}
+	}
+
+	cursor := ""
+	if req.Cursor != nil {
+		cursor = *req.Cursor
+	}
+
+	offset, ok := decodeCursor(cursor)
+	if !ok {
+		writeBadRequest(w, square.ErrorCodeInvalidCursor, "The provided cursor is invalid.", "cursor")
+		return
+	}
+
+	var updatedAfter, updatedBefore time.Time
+	for field, bound := range map[string]struct {
+		value  *string
+		parsed *time.Time
+	}{"updated_after": {req.UpdatedAfter, &updatedAfter}, "updated_before": {req.UpdatedBefore, &updatedBefore}} {
+		if bound.value == nil {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, *bound.value)
+		if err != nil {
+			writeBadRequest(w, square.ErrorCodeInvalidValue, "`"+field+"` must be an RFC 3339 timestamp.", field)
+			return
+		}
+		*bound.parsed = parsed
+	}
+
+	objectFilter := toSet(req.CatalogObjectIDs)
+	locationFilter := toSet(req.LocationIDs)
+	typeFilter := map[square.InventoryChangeType]bool{}
+	for _, changeType := range req.Types {
+		typeFilter[changeType] = true
+	}
+	stateFilter := map[square.InventoryState]bool{}
+	for _, state := range req.States {
+		stateFilter[state] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type match struct {
+		change     *square.InventoryChange
+		occurredAt string
+	}
+
+	matches := []match{}
+	for _, change := range s.changes {
+		catalogObjectID, locationID, states, occurredAt, createdAt := changeFields(change)
+		if len(objectFilter) > 0 && !objectFilter[catalogObjectID] {
+			continue
+		}
+		if len(locationFilter) > 0 && !locationFilter[locationID] {
+			continue
+		}
+		if len(typeFilter) > 0 && (change.Type == nil || !typeFilter[*change.Type]) {
+			continue
+		}
+		if len(stateFilter) > 0 && !anyState(states, stateFilter) {
+			continue
+		}
+
+		// Square filters on when a change was recorded rather than when it occurred.
+		created, _ := time.Parse(time.RFC3339Nano, createdAt)
+		if !updatedAfter.IsZero() && created.Before(updatedAfter) {
+			continue
+		}
+		if !updatedBefore.IsZero() && !created.Before(updatedBefore) {
+			continue
+		}
+
+		matches = append(matches, match{change, occurredAt})
+	}
+
+	// Square returns the oldest changes first. The stable sort keeps insertion order for ties so
+	// cursors stay stable.
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, _ := time.Parse(time.RFC3339Nano, matches[i].occurredAt)
+		b, _ := time.Parse(time.RFC3339Nano, matches[j].occurredAt)
+		return a.Before(b)
+	})
+
+	if offset > len(matches) {
+		writeBadRequest(w, square.ErrorCodeInvalidCursor, "The provided cursor is invalid.", "cursor")
+		return
+	}
+
+	end := min(offset+limit, len(matches))
+	changes := []*square.InventoryChange{}
+	for _, m := range matches[offset:end] {
+		changes = append(changes, m.change)
+	}
+
+	resp := &square.BatchGetInventoryChangesResponse{Changes: changes}
+	if end < len(matches) {
+		resp.Cursor = square.String(encodeCursor(end))
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func anyState(states []square.InventoryState, filter map[square.InventoryState]bool) bool {
+	for _, state := range states {
+		if filter[state] {
+			return true
+		}
+	}
+	return false
+}
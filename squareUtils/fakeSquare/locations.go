@@ -0,0 +1,45 @@
+package fakeSquare
+
+import (
+	"net/http"
+	"sort"
+
+	square "github.com/square/square-go-sdk"
+)
+
+// AddLocation adds an active location that stock can be counted and adjusted at.
+func (s *Server) AddLocation(locationID, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := square.LocationStatusActive
+	currency := square.CurrencyUsd
+	s.locations = append(s.locations, &square.Location{
+		ID:       square.String(locationID),
+		Name:     square.String(name),
+		Status:   &status,
+		Currency: &currency,
+	})
+}
+
+func (s *Server) handleListLocations(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Square lists locations alphabetically by name.
+	locations := append([]*square.Location{}, s.locations...)
+	sort.Slice(locations, func(i, j int) bool {
+		return *locations[i].Name < *locations[j].Name
+	})
+
+	writeJSON(w, http.StatusOK, &square.ListLocationsResponse{Locations: locations})
+}
+
+func (s *Server) knownLocation(locationID string) bool {
+	for _, location := range s.locations {
+		if *location.ID == locationID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,514 @@
+package squareUtils
+
+import (
+	"aoa-inventory/audit"
+	"aoa-inventory/squareUtils/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	square "github.com/square/square-go-sdk"
+)
+
+// FileLocationID is the single location the file backend keeps stock at.
+const FileLocationID = "local"
+
+// FileBackend serves inventory from a local JSON file such as data.json, so the
+// server can run without Square credentials.
+type FileBackend struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileBackend(path string) *FileBackend {
+	return &FileBackend{path: path}
+}
+
+func (b *FileBackend) ListInventory(ctx context.Context, query InventoryQuery) ([]models.InventoryItem, error) {
+	if err := checkFileLocation(query.LocationID, true); err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	items, err := b.readItems()
+	if err != nil {
+		return nil, err
+	}
+
+	listed := []models.InventoryItem{}
+	for i := range items {
+		if items[i].Archived && !query.IncludeArchived {
+			continue
+		}
+		withLocationBreakdown(&items[i], query)
+		listed = append(listed, items[i])
+	}
+
+	SortInventory(listed, SortByName, false)
+	return listed, nil
+}
+
+func (b *FileBackend) GetInventoryItem(ctx context.Context, sku string, query InventoryQuery) (*models.InventoryItem, error) {
+	if sku == "" {
+		return nil, errors.New("sku is required")
+	}
+
+	if err := checkFileLocation(query.LocationID, true); err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	items, err := b.readItems()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range items {
+		if items[i].SKU == sku {
+			withLocationBreakdown(&items[i], query)
+			return &items[i], nil
+		}
+	}
+
+	return nil, ErrInventoryItemNotFound
+}
+
+func (b *FileBackend) GetInventoryItemByVariationID(ctx context.Context, variationID string, query InventoryQuery) (*models.InventoryItem, error) {
+	if variationID == "" {
+		return nil, errors.New("variation ID is required")
+	}
+
+	if err := checkFileLocation(query.LocationID, true); err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	items, err := b.readItems()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range items {
+		if items[i].ID == variationID {
+			withLocationBreakdown(&items[i], query)
+			return &items[i], nil
+		}
+	}
+
+	return nil, ErrInventoryItemNotFound
+}
+
+func (b *FileBackend) UpdateInventoryItem(ctx context.Context, locationID, sku string, update *models.InventoryItemUpdate) (*models.InventoryItem, error) {
+	if update == nil {
+		return nil, errors.New("update is required")
+	}
+
+	if err := checkFileLocation(locationID, false); err != nil {
+		return nil, err
+	}
+
+	if update.CurrentStock == nil && !HasCatalogFields(update) {
+		return nil, fmt.Errorf("%w: nothing to update", ErrInvalidItemUpdate)
+	}
+
+	if update.ExpectedCatalogVersion != nil {
+		return nil, fmt.Errorf("catalog versions are %w", ErrNotSupported)
+	}
+
+	reason, _, err := parseStockUpdate(update)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkPriceUpdate(update.Price); err != nil {
+		return nil, err
+	}
+	if update.InventoryAlert, err = normalizeInventoryAlert(update.InventoryAlert); err != nil {
+		return nil, err
+	}
+	if update.Price != nil && update.Price.Amount != nil {
+		money, err := squareMoney(*update.Price.Amount, update.Price.Currency, defaultCurrency)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidItemUpdate, err)
+		}
+		price := *update.Price
+		price.Currency = string(*money.Currency)
+		update.Price = &price
+	}
+
+	updatedItem, err := b.modifyItem(sku, func(item *models.InventoryItem) error {
+		current := *item
+		current.Version = fileStockVersion(current)
+		if err := checkExpectedStock(update, current); err != nil {
+			return err
+		}
+
+		// As with Square, the ID and image are fixed, but clients may send back their values.
+		if update.ID != nil && *update.ID != item.ID {
+			return fmt.Errorf("%w: id cannot be changed", ErrInvalidItemUpdate)
+		}
+		if update.ImageURL != nil && *update.ImageURL != item.ImageURL {
+			return fmt.Errorf("%w: imageUrl cannot be changed", ErrInvalidItemUpdate)
+		}
+		if update.Name != nil && strings.TrimSpace(*update.Name) == "" {
+			return fmt.Errorf("%w: name cannot be empty", ErrInvalidItemUpdate)
+		}
+
+		if update.CurrentStock != nil {
+			if err := reason.checkDirection(*update.CurrentStock - item.CurrentStock); err != nil {
+				return err
+			}
+		}
+
+		oldStock := item.CurrentStock
+		item.ApplyUpdate(update)
+		noteFileChange(ctx, item, string(reason), oldStock)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	updatedItem.Version = fileStockVersion(*updatedItem)
+	return updatedItem, nil
+}
+
+func (b *FileBackend) CatalogChanges(ctx context.Context, locationID, sku string, update *models.InventoryItemUpdate) ([]string, error) {
+	item, err := b.GetInventoryItem(ctx, sku, InventoryQuery{LocationID: locationID})
+	if err != nil {
+		return nil, err
+	}
+
+	// The file keeps a single price per item, which base and location updates both replace.
+	return changedCatalogFields(update, item, item.Price), nil
+}
+
+func (b *FileBackend) AdjustInventoryItem(ctx context.Context, locationID, sku string, delta int, reason AdjustmentReason) (*models.InventoryItem, error) {
+	if err := checkFileLocation(locationID, false); err != nil {
+		return nil, err
+	}
+
+	if err := reason.checkAdjustment(delta); err != nil {
+		return nil, err
+	}
+
+	updatedItem, err := b.modifyItem(sku, func(item *models.InventoryItem) error {
+		item.CurrentStock += delta
+		noteFileChange(ctx, item, string(reason), item.CurrentStock-delta)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	updatedItem.Version = fileStockVersion(*updatedItem)
+	return updatedItem, nil
+}
+
+// BatchUpdateInventory applies every valid change and writes the file once. Since nothing is
+// written if the write fails, that is reported as an error for the whole batch.
+func (b *FileBackend) BatchUpdateInventory(ctx context.Context, locationID string, changes []models.StockChange) ([]StockChangeOutcome, error) {
+	if err := checkFileLocation(locationID, false); err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	items, err := b.readItems()
+	if err != nil {
+		return nil, err
+	}
+
+	indexBySKU := map[string]int{}
+	for i := range items {
+		indexBySKU[items[i].SKU] = i
+	}
+
+	outcomes := make([]StockChangeOutcome, len(changes))
+	seen := map[string]bool{}
+	for i, change := range changes {
+		outcomes[i].SKU = change.SKU
+
+		reason, err := parseStockChange(change)
+		if err != nil {
+			outcomes[i].Err = err
+			continue
+		}
+
+		if seen[change.SKU] {
+			outcomes[i].Err = fmt.Errorf("%w: sku %s appears more than once", ErrInvalidAdjustment, change.SKU)
+			continue
+		}
+		seen[change.SKU] = true
+
+		itemIndex, ok := indexBySKU[change.SKU]
+		if !ok {
+			outcomes[i].Err = ErrInventoryItemNotFound
+			continue
+		}
+
+		item := &items[itemIndex]
+		oldStock := item.CurrentStock
+		if change.Delta != nil {
+			item.CurrentStock += *change.Delta
+		} else {
+			if err := reason.checkDirection(*change.CurrentStock - item.CurrentStock); err != nil {
+				outcomes[i].Err = err
+				continue
+			}
+			item.CurrentStock = *change.CurrentStock
+		}
+		noteFileChange(ctx, item, string(reason), oldStock)
+
+		updatedItem := *item
+		updatedItem.Version = fileStockVersion(updatedItem)
+		outcomes[i].Item = &updatedItem
+	}
+
+	if err := b.writeItems(items); err != nil {
+		return nil, err
+	}
+
+	return outcomes, nil
+}
+
+// CreateInventoryItem appends one item per variation.
+func (b *FileBackend) CreateInventoryItem(ctx context.Context, locationID string, newItem *models.NewInventoryItem) ([]models.InventoryItem, error) {
+	variations, err := newItemVariations(newItem)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkFileLocation(locationID, false); err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	items, err := b.readItems()
+	if err != nil {
+		return nil, err
+	}
+
+	usedIDs := map[string]bool{}
+	for _, item := range items {
+		usedIDs[item.ID] = true
+		for _, variation := range variations {
+			if item.SKU == variation.SKU {
+				return nil, fmt.Errorf("%w: %s", ErrDuplicateSKU, variation.SKU)
+			}
+		}
+	}
+
+	created := []models.InventoryItem{}
+	nextID := len(items) + 1
+	for _, variation := range variations {
+		for usedIDs["p"+strconv.Itoa(nextID)] {
+			nextID++
+		}
+		id := "p" + strconv.Itoa(nextID)
+		usedIDs[id] = true
+
+		price := &models.Price{PricingType: string(square.CatalogPricingTypeVariablePricing)}
+		if variation.Price != nil {
+			money, err := squareMoney(variation.Price.Amount, variation.Price.Currency, defaultCurrency)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrInvalidNewItem, err)
+			}
+			price = variationPrice{money: money}.apiPrice()
+		}
+
+		created = append(created, models.InventoryItem{
+			ID:                id,
+			Name:              newItem.Name,
+			Description:       newItem.Description,
+			VariationName:     variation.Name,
+			SKU:               variation.SKU,
+			CurrentStock:      variation.CurrentStock,
+			Category:          newItem.Category,
+			ReportingCategory: newItem.ReportingCategory,
+			Price:             price,
+		})
+	}
+
+	if err := b.writeItems(append(items, created...)); err != nil {
+		return nil, err
+	}
+
+	for i := range created {
+		created[i].Version = fileStockVersion(created[i])
+
+		audit.Note(ctx, created[i].SKU, func(record *models.AuditRecord) {
+			record.VariationID, record.NewStock = created[i].ID, &created[i].CurrentStock
+		})
+	}
+
+	return created, nil
+}
+
+// DeleteInventoryItem marks the item archived, or removes it from the file for a hard delete.
+func (b *FileBackend) DeleteInventoryItem(ctx context.Context, sku string, hard bool) error {
+	if !hard {
+		_, err := b.modifyItem(sku, func(item *models.InventoryItem) error {
+			item.Archived = true
+			return nil
+		})
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	items, err := b.readItems()
+	if err != nil {
+		return err
+	}
+
+	for i := range items {
+		if items[i].SKU == sku {
+			return b.writeItems(slices.Delete(items, i, i+1))
+		}
+	}
+
+	return ErrInventoryItemNotFound
+}
+
+// UploadInventoryItemImage is not supported, since the file backend has nowhere to host images.
+func (b *FileBackend) UploadInventoryItemImage(ctx context.Context, locationID, sku string, upload *ImageUpload) (*models.InventoryItem, error) {
+	return nil, fmt.Errorf("image uploads are %w", ErrNotSupported)
+}
+
+// InventoryHistory is not supported, since the file only keeps current stock.
+func (b *FileBackend) InventoryHistory(ctx context.Context, sku string, query HistoryQuery) (*models.InventoryHistory, error) {
+	return nil, fmt.Errorf("inventory history is %w", ErrNotSupported)
+}
+
+func (b *FileBackend) ListLocations(ctx context.Context) ([]models.Location, error) {
+	return []models.Location{{
+		ID:        FileLocationID,
+		Name:      "Local inventory file",
+		Status:    "ACTIVE",
+		IsDefault: true,
+	}}, nil
+}
+
+// checkFileLocation rejects any location other than the file backend's single location.
+func checkFileLocation(locationID string, allowAll bool) error {
+	if locationID == "" || locationID == FileLocationID || (allowAll && locationID == AllLocations) {
+		return nil
+	}
+	return ErrLocationNotFound
+}
+
+// withLocationBreakdown fills in the version and the location and state breakdowns requested by
+// the query. The file only records IN_STOCK quantities, so every other state is reported as zero.
+func withLocationBreakdown(item *models.InventoryItem, query InventoryQuery) {
+	item.Version = fileStockVersion(*item)
+
+	if len(query.States) > 0 {
+		item.States = map[string]int{}
+		for _, state := range query.States {
+			item.States[state] = 0
+			if state == string(square.InventoryStateInStock) {
+				item.States[state] = item.CurrentStock
+			}
+		}
+	}
+
+	if query.LocationID == AllLocations {
+		item.Locations = []models.LocationStock{{LocationID: FileLocationID, CurrentStock: item.CurrentStock, States: item.States}}
+	}
+}
+
+// noteFileChange fills in the audit record of a stock write to the file.
+func noteFileChange(ctx context.Context, item *models.InventoryItem, reason string, oldStock int) {
+	newStock := item.CurrentStock
+
+	audit.Note(ctx, item.SKU, func(record *models.AuditRecord) {
+		record.VariationID, record.Reason = item.ID, reason
+		record.OldStock, record.NewStock = &oldStock, &newStock
+	})
+}
+
+// fileStockVersion versions an item by its stock alone, since the file keeps no change times.
+func fileStockVersion(item models.InventoryItem) string {
+	return stockVersion(nil, item.CurrentStock)
+}
+
+// modifyItem applies fn to the item with the given SKU and writes the file back. Nothing is
+// written if fn returns an error.
+func (b *FileBackend) modifyItem(sku string, fn func(item *models.InventoryItem) error) (*models.InventoryItem, error) {
+	if sku == "" {
+		return nil, errors.New("sku is required")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	items, err := b.readItems()
+	if err != nil {
+		return nil, err
+	}
+
+	var updatedItem models.InventoryItem
+	itemUpdated := false
+	for i := range items {
+		if items[i].SKU == sku {
+			if err := fn(&items[i]); err != nil {
+				return nil, err
+			}
+			updatedItem = items[i]
+			itemUpdated = true
+			break
+		}
+	}
+
+	if !itemUpdated {
+		return nil, ErrInventoryItemNotFound
+	}
+
+	if err := b.writeItems(items); err != nil {
+		return nil, err
+	}
+
+	return &updatedItem, nil
+}
+
+func (b *FileBackend) readItems() ([]models.InventoryItem, error) {
+	data, err := os.ReadFile(b.path)
+	if err != nil {
+		log.Printf("Could not read %s: %v", b.path, err)
+		return nil, err
+	}
+
+	items := []models.InventoryItem{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		log.Printf("Could not parse %s: %v", b.path, err)
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (b *FileBackend) writeItems(items []models.InventoryItem) error {
+	updatedData, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(b.path, updatedData, 0644)
+}
@@ -0,0 +1,69 @@
+package squareUtils
+
+import (
+	"aoa-inventory/squareUtils/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newFileBackend(t *testing.T) *FileBackend {
+	t.Helper()
+
+	data, err := json.Marshal(testItems)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "data.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return NewFileBackend(path)
+}
+
+func TestFileBackendUpdatePersists(t *testing.T) {
+	backend := newFileBackend(t)
+
+	updateItem(t, backend, "LAT-001", &models.InventoryItemUpdate{
+		Name:         ptr("Oat Latte"),
+		CurrentStock: ptr(6),
+		Price:        &models.PriceUpdate{Amount: ptr[int64](475)},
+		// Sent back unchanged, as clients do.
+		ID: ptr("VAR_LATTE"),
+	})
+
+	item := getItem(t, NewFileBackend(backend.path), "LAT-001", "")
+	if item.Name != "Oat Latte" || item.CurrentStock != 6 || priceAmount(item.Price) != 475 {
+		t.Errorf("item read back = %q with %d at %d, want Oat Latte with 6 at 475", item.Name, item.CurrentStock, priceAmount(item.Price))
+	}
+	if item.ID != "VAR_LATTE" || item.Category != "Drink" {
+		t.Errorf("item read back = %q in %q, want the untouched VAR_LATTE in Drink", item.ID, item.Category)
+	}
+}
+
+func TestFileBackendRejectsInvalidUpdates(t *testing.T) {
+	backend := newFileBackend(t)
+
+	tests := map[string]models.InventoryItemUpdate{
+		"empty":      {},
+		"id":         {ID: ptr("VAR_OTHER")},
+		"image":      {ImageURL: ptr("https://example.com/latte.png")},
+		"empty name": {Name: ptr(" ")},
+	}
+
+	for name, update := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := backend.UpdateInventoryItem(context.Background(), "", "LAT-001", &update)
+			if !errors.Is(err, ErrInvalidItemUpdate) {
+				t.Errorf("err = %v, want ErrInvalidItemUpdate", err)
+			}
+		})
+	}
+
+	if item := getItem(t, backend, "LAT-001", ""); item.ID != "VAR_LATTE" || item.ImageURL != "" || item.Name != "Vanilla Latte" {
+		t.Errorf("item = %+v, want it unchanged", item)
+	}
+}
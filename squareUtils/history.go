@@ -0,0 +1,163 @@
+package squareUtils
+
+import (
+	"aoa-inventory/squareUtils/client"
+	"aoa-inventory/squareUtils/models"
+	"context"
+	"time"
+
+	square "github.com/square/square-go-sdk"
+)
+
+// HistoryQuery selects a page of a SKU's stock history.
+type HistoryQuery struct {
+	// LocationID is the location to read changes at, as in InventoryQuery.
+	LocationID string
+
+	// Since and Until limit the changes to those recorded in that window. Zero means no limit.
+	Since time.Time
+	Until time.Time
+
+	Cursor string
+	Limit  int
+}
+
+// InventoryHistory returns a page of the adjustments, physical counts and transfers Square
+// recorded for the SKU.
+func (b *SquareBackend) InventoryHistory(ctx context.Context, sku string, query HistoryQuery) (*models.InventoryHistory, error) {
+	locationIDs, err := b.resolveLocations(ctx, query.LocationID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, variationID, err := b.resolveSKU(ctx, sku)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, cursor, err := fetchInventoryChanges(ctx, variationID, locationIDs, query)
+	if err != nil {
+		log.Printf("ERROR: Failed to fetch inventory changes from Square: %v", err)
+		return nil, err
+	}
+
+	history := &models.InventoryHistory{SKU: sku, Changes: []models.InventoryChange{}, Cursor: cursor}
+	for _, change := range changes {
+		if entry, ok := historyEntry(change); ok {
+			history.Changes = append(history.Changes, entry)
+		}
+	}
+
+	return history, nil
+}
+
+// fetchInventoryChanges returns one page of changes for the variation and the cursor of the next.
+func fetchInventoryChanges(ctx context.Context, variationID string, locationIDs []string, query HistoryQuery) ([]*square.InventoryChange, string, error) {
+	sqClient := client.SquareClient
+	if sqClient == nil {
+		return nil, "", errSquareClientNotInitialized
+	}
+
+	req := &square.BatchRetrieveInventoryChangesRequest{
+		CatalogObjectIDs: []string{variationID},
+		LocationIDs:      locationIDs,
+	}
+	if !query.Since.IsZero() {
+		req.UpdatedAfter = square.String(query.Since.UTC().Format(time.RFC3339))
+	}
+	if !query.Until.IsZero() {
+		req.UpdatedBefore = square.String(query.Until.UTC().Format(time.RFC3339))
+	}
+	if query.Cursor != "" {
+		req.Cursor = square.String(query.Cursor)
+	}
+	if query.Limit > 0 {
+		req.Limit = square.Int(query.Limit)
+	}
+
+	changesResp, err := sqClient.Inventory.BatchGetChanges(ctx, req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	cursor := ""
+	if changesResp.Cursor != nil {
+		cursor = *changesResp.Cursor
+	}
+
+	return changesResp.Changes, cursor, nil
+}
+
+// historyEntry converts a Square change to the API model. Changes of unknown types are skipped.
+func historyEntry(change *square.InventoryChange) (models.InventoryChange, bool) {
+	var entry models.InventoryChange
+	var quantity *string
+	var source *square.SourceApplication
+
+	switch {
+	case change == nil:
+		return entry, false
+	case change.Adjustment != nil:
+		adjustment := change.Adjustment
+		entry = models.InventoryChange{
+			ID:          deref(adjustment.ID),
+			Type:        string(square.InventoryChangeTypeAdjustment),
+			OccurredAt:  deref(adjustment.OccurredAt),
+			FromState:   deref(adjustment.FromState),
+			ToState:     deref(adjustment.ToState),
+			LocationID:  deref(adjustment.LocationID),
+			ReferenceID: deref(adjustment.ReferenceID),
+		}
+		quantity, source = adjustment.Quantity, adjustment.Source
+	case change.PhysicalCount != nil:
+		physicalCount := change.PhysicalCount
+		entry = models.InventoryChange{
+			ID:          deref(physicalCount.ID),
+			Type:        string(square.InventoryChangeTypePhysicalCount),
+			OccurredAt:  deref(physicalCount.OccurredAt),
+			ToState:     deref(physicalCount.State),
+			LocationID:  deref(physicalCount.LocationID),
+			ReferenceID: deref(physicalCount.ReferenceID),
+		}
+		quantity, source = physicalCount.Quantity, physicalCount.Source
+	case change.Transfer != nil:
+		transfer := change.Transfer
+		entry = models.InventoryChange{
+			ID:             deref(transfer.ID),
+			Type:           string(square.InventoryChangeTypeTransfer),
+			OccurredAt:     deref(transfer.OccurredAt),
+			ToState:        deref(transfer.State),
+			FromLocationID: deref(transfer.FromLocationID),
+			ToLocationID:   deref(transfer.ToLocationID),
+			ReferenceID:    deref(transfer.ReferenceID),
+		}
+		quantity, source = transfer.Quantity, transfer.Source
+	default:
+		return entry, false
+	}
+
+	if quantity != nil {
+		if qty, err := parseQuantity(*quantity); err == nil {
+			entry.Quantity = qty
+		}
+	}
+
+	if source != nil {
+		switch {
+		case source.Name != nil:
+			entry.Source = *source.Name
+		case source.Product != nil:
+			entry.Source = string(*source.Product)
+		}
+	}
+
+	return entry, true
+}
+
+// deref returns the string a Square field points to, or "" when it is unset.
+func deref[T ~string](value *T) string {
+	if value == nil {
+		return ""
+	}
+	return string(*value)
+}
@@ -0,0 +1,61 @@
+package squareUtils
+
+import (
+	"aoa-inventory/squareUtils/models"
+	"context"
+	"testing"
+)
+
+func TestInventoryHistoryPages(t *testing.T) {
+	backend, _ := newFakeBackend(t)
+	ctx := context.Background()
+
+	if _, err := backend.AdjustInventoryItem(ctx, "", "LAT-001", 3, ReasonReceived); err != nil {
+		t.Fatalf("AdjustInventoryItem: %v", err)
+	}
+	if _, err := backend.AdjustInventoryItem(ctx, "", "BAK-002", -1, ReasonWaste); err != nil {
+		t.Fatalf("AdjustInventoryItem: %v", err)
+	}
+	if _, err := backend.AdjustInventoryItem(ctx, "", "LAT-001", -2, ReasonSold); err != nil {
+		t.Fatalf("AdjustInventoryItem: %v", err)
+	}
+	updateItem(t, backend, "LAT-001", &models.InventoryItemUpdate{CurrentStock: ptr(9)})
+
+	changes := []models.InventoryChange{}
+	query := HistoryQuery{Limit: 2}
+	for pages := 1; ; pages++ {
+		history, err := backend.InventoryHistory(ctx, "LAT-001", query)
+		if err != nil {
+			t.Fatalf("InventoryHistory page %d: %v", pages, err)
+		}
+		if len(history.Changes) > query.Limit {
+			t.Fatalf("page %d has %d changes, want at most %d", pages, len(history.Changes), query.Limit)
+		}
+		changes = append(changes, history.Changes...)
+
+		if history.Cursor == "" {
+			break
+		}
+		if pages == 10 {
+			t.Fatal("history did not end after 10 pages")
+		}
+		query.Cursor = history.Cursor
+	}
+
+	// Oldest first, without the croissant's waste.
+	want := []models.InventoryChange{
+		{Type: "ADJUSTMENT", FromState: "NONE", ToState: "IN_STOCK", Quantity: 3},
+		{Type: "ADJUSTMENT", FromState: "IN_STOCK", ToState: "SOLD", Quantity: 2},
+		{Type: "PHYSICAL_COUNT", ToState: "IN_STOCK", Quantity: 9},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("got %d changes, want %d: %+v", len(changes), len(want), changes)
+	}
+	for i, change := range changes {
+		if change.Type != want[i].Type || change.FromState != want[i].FromState || change.ToState != want[i].ToState || change.Quantity != want[i].Quantity {
+			t.Errorf("change %d = %s %s->%s %d, want %s %s->%s %d", i,
+				change.Type, change.FromState, change.ToState, change.Quantity,
+				want[i].Type, want[i].FromState, want[i].ToState, want[i].Quantity)
+		}
+	}
+}
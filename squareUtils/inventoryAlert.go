@@ -0,0 +1,85 @@
+package squareUtils
+
+import (
+	"aoa-inventory/squareUtils/models"
+	"fmt"
+	"strings"
+
+	square "github.com/square/square-go-sdk"
+)
+
+// normalizeInventoryAlert validates an alert from an update. The type defaults to LOW_QUANTITY,
+// and a NONE alert has no threshold.
+func normalizeInventoryAlert(alert *models.InventoryAlert) (*models.InventoryAlert, error) {
+	if alert == nil {
+		return nil, nil
+	}
+
+	normalized := *alert
+	if normalized.Type == "" {
+		normalized.Type = string(square.InventoryAlertTypeLowQuantity)
+	}
+
+	alertType, err := square.NewInventoryAlertTypeFromString(strings.ToUpper(normalized.Type))
+	if err != nil {
+		return nil, fmt.Errorf("%w: inventoryAlert type must be NONE or LOW_QUANTITY", ErrInvalidItemUpdate)
+	}
+	normalized.Type = string(alertType)
+
+	if normalized.Threshold < 0 {
+		return nil, fmt.Errorf("%w: inventoryAlert threshold cannot be negative", ErrInvalidItemUpdate)
+	}
+	if alertType == square.InventoryAlertTypeNone {
+		normalized.Threshold = 0
+	}
+
+	return &normalized, nil
+}
+
+// squareInventoryAlert reads an alert as Square stores it, or nil when none is set.
+func squareInventoryAlert(alertType *square.InventoryAlertType, threshold *int64) *models.InventoryAlert {
+	if alertType == nil {
+		return nil
+	}
+
+	alert := &models.InventoryAlert{Type: string(*alertType)}
+	if threshold != nil && *alertType == square.InventoryAlertTypeLowQuantity {
+		alert.Threshold = int(*threshold)
+	}
+	return alert
+}
+
+// inventoryAlert returns the variation's alert at the location, from its override there if that
+// sets one. An empty locationID returns the variation's own alert.
+func (idx *catalogIndex) inventoryAlert(variationID, locationID string) *models.InventoryAlert {
+	meta := idx.variationDetails[variationID]
+	if alert, ok := meta.locationAlerts[locationID]; ok {
+		return alert
+	}
+	return meta.alert
+}
+
+// alertUnchanged reports whether the update would set the alert the item already has.
+func alertUnchanged(update, current *models.InventoryAlert) bool {
+	if current == nil {
+		return update.Type == string(square.InventoryAlertTypeNone)
+	}
+	return *update == *current
+}
+
+// setInventoryAlert writes the alert to the variation's override at the location, where Square
+// keeps per-location alerts.
+func setInventoryAlert(variationData *square.CatalogItemVariation, locationID string, alert *models.InventoryAlert) {
+	override := locationOverride(variationData, locationID, true)
+	override.InventoryAlertType = square.InventoryAlertType(alert.Type).Ptr()
+	override.InventoryAlertThreshold = nil
+	if alert.Type == string(square.InventoryAlertTypeLowQuantity) {
+		override.InventoryAlertThreshold = square.Int64(int64(alert.Threshold))
+	}
+}
+
+// IsLowStock reports whether the item has a low-quantity alert and is at or below its threshold.
+func IsLowStock(item models.InventoryItem) bool {
+	alert := item.InventoryAlert
+	return alert != nil && alert.Type == string(square.InventoryAlertTypeLowQuantity) && item.CurrentStock <= alert.Threshold
+}
@@ -0,0 +1,103 @@
+package squareUtils
+
+import (
+	"aoa-inventory/squareUtils/models"
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// Keys inventory can be sorted by.
+const (
+	SortByName     = "name"
+	SortBySKU      = "sku"
+	SortByStock    = "stock"
+	SortByCategory = "category"
+)
+
+// SortKeys lists the keys SortInventory accepts.
+var SortKeys = []string{SortByName, SortBySKU, SortByStock, SortByCategory}
+
+// InventoryFilter narrows a list of inventory items. Empty fields match everything; text
+// matches ignore case.
+type InventoryFilter struct {
+	Category          string
+	ReportingCategory string
+
+	// Search matches a substring of the name, variation name or SKU.
+	Search string
+
+	MinStock    *int
+	MaxStock    *int
+	InStockOnly bool
+}
+
+// FilterInventory returns the items matching the filter, keeping their order.
+func FilterInventory(items []models.InventoryItem, filter InventoryFilter) []models.InventoryItem {
+	search := strings.ToLower(filter.Search)
+
+	matched := []models.InventoryItem{}
+	for _, item := range items {
+		switch {
+		case filter.Category != "" && !strings.EqualFold(item.Category, filter.Category):
+		case filter.ReportingCategory != "" && !strings.EqualFold(item.ReportingCategory, filter.ReportingCategory):
+		case search != "" && !strings.Contains(strings.ToLower(item.Name), search) &&
+			!strings.Contains(strings.ToLower(item.VariationName), search) &&
+			!strings.Contains(strings.ToLower(item.SKU), search):
+		case filter.MinStock != nil && item.CurrentStock < *filter.MinStock:
+		case filter.MaxStock != nil && item.CurrentStock > *filter.MaxStock:
+		case filter.InStockOnly && item.CurrentStock <= 0:
+		default:
+			matched = append(matched, item)
+		}
+	}
+	return matched
+}
+
+// SortInventory sorts the items by one of SortKeys, falling back to the name. Ties are broken by
+// name, variation name and SKU, so the order is the same on every read.
+func SortInventory(items []models.InventoryItem, sortBy string, descending bool) {
+	slices.SortFunc(items, func(a, b models.InventoryItem) int {
+		return CompareInventory(a, b, sortBy, descending)
+	})
+}
+
+// CompareInventory orders two items the way SortInventory does.
+func CompareInventory(a, b models.InventoryItem, sortBy string, descending bool) int {
+	primary := 0
+	switch sortBy {
+	case SortBySKU:
+		primary = compareFolded(a.SKU, b.SKU)
+	case SortByStock:
+		primary = cmp.Compare(a.CurrentStock, b.CurrentStock)
+	case SortByCategory:
+		primary = compareFolded(a.Category, b.Category)
+	}
+
+	order := cmp.Or(
+		primary,
+		compareFolded(a.Name, b.Name),
+		compareFolded(a.VariationName, b.VariationName),
+		cmp.Compare(a.SKU, b.SKU),
+	)
+	if descending {
+		return -order
+	}
+	return order
+}
+
+// InventoryAfter returns the items that sort after the given one, which need not be in the list
+// any more. The items must already be sorted the same way, as by SortInventory.
+func InventoryAfter(items []models.InventoryItem, after models.InventoryItem, sortBy string, descending bool) []models.InventoryItem {
+	i, _ := slices.BinarySearchFunc(items, after, func(item, target models.InventoryItem) int {
+		if CompareInventory(item, target, sortBy, descending) <= 0 {
+			return -1
+		}
+		return 1
+	})
+	return items[i:]
+}
+
+func compareFolded(a, b string) int {
+	return cmp.Or(cmp.Compare(strings.ToLower(a), strings.ToLower(b)), cmp.Compare(a, b))
+}
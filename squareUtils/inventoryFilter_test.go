@@ -0,0 +1,74 @@
+package squareUtils
+
+import (
+	"aoa-inventory/squareUtils/models"
+	"slices"
+	"testing"
+)
+
+func skus(items []models.InventoryItem) []string {
+	result := []string{}
+	for _, item := range items {
+		result = append(result, item.SKU)
+	}
+	return result
+}
+
+func TestSortInventory(t *testing.T) {
+	items := []models.InventoryItem{
+		{Name: "banana", SKU: "B2", CurrentStock: 3, Category: "Fruit"},
+		{Name: "Apple", SKU: "A1", CurrentStock: 3, Category: "Fruit"},
+		{Name: "Banana", VariationName: "Large", SKU: "B1", CurrentStock: 1, Category: "Fruit"},
+		{Name: "Carrot", SKU: "C1", CurrentStock: 9, Category: "Veg"},
+	}
+
+	tests := []struct {
+		sortBy     string
+		descending bool
+		want       []string
+	}{
+		{SortByName, false, []string{"A1", "B1", "B2", "C1"}},
+		{SortBySKU, false, []string{"A1", "B1", "B2", "C1"}},
+		{SortByStock, false, []string{"B1", "A1", "B2", "C1"}},
+		{SortByStock, true, []string{"C1", "B2", "A1", "B1"}},
+		{SortByCategory, false, []string{"A1", "B1", "B2", "C1"}},
+	}
+
+	for _, tt := range tests {
+		sorted := slices.Clone(items)
+		SortInventory(sorted, tt.sortBy, tt.descending)
+		if got := skus(sorted); !slices.Equal(got, tt.want) {
+			t.Errorf("sort %s descending=%t = %v, want %v", tt.sortBy, tt.descending, got, tt.want)
+		}
+	}
+}
+
+func TestInventoryAfter(t *testing.T) {
+	items := []models.InventoryItem{
+		{Name: "Apple", SKU: "A1", CurrentStock: 1},
+		{Name: "Banana", SKU: "B1", CurrentStock: 2},
+		{Name: "Cherry", SKU: "C1", CurrentStock: 2},
+		{Name: "Damson", SKU: "D1", CurrentStock: 5},
+	}
+
+	tests := []struct {
+		name       string
+		sortBy     string
+		descending bool
+		after      models.InventoryItem
+		want       []string
+	}{
+		{"after a listed item", SortByStock, false, items[1], []string{"C1", "D1"}},
+		{"after a removed item", SortByStock, false, models.InventoryItem{Name: "Blueberry", SKU: "BL", CurrentStock: 2}, []string{"C1", "D1"}},
+		{"after the last item", SortByStock, false, items[3], []string{}},
+		{"descending", SortByStock, true, items[2], []string{"B1", "A1"}},
+	}
+
+	for _, tt := range tests {
+		sorted := slices.Clone(items)
+		SortInventory(sorted, tt.sortBy, tt.descending)
+		if got := skus(InventoryAfter(sorted, tt.after, tt.sortBy, tt.descending)); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package squareUtils
+
+import (
+	"aoa-inventory/audit"
+	"aoa-inventory/squareUtils/client"
+	"aoa-inventory/squareUtils/models"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	square "github.com/square/square-go-sdk"
+	"github.com/square/square-go-sdk/catalog"
+)
+
+// MaxImageSize is the largest image file Square accepts.
+const MaxImageSize = 15 << 20
+
+var ErrInvalidImage = errors.New("invalid image")
+
+var ErrImageTooLarge = fmt.Errorf("image is larger than %d MB", MaxImageSize>>20)
+
+var ErrNotSupported = errors.New("not supported by this inventory backend")
+
+// imageContentTypes are the image formats Square accepts, as detected from the file contents.
+var imageContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// ImageUpload is an image file to attach to an item.
+type ImageUpload struct {
+	Filename string
+	Data     []byte
+
+	// Primary makes the image the first one shown for the item or variation.
+	Primary bool
+
+	// ForVariation attaches the image to the SKU's variation instead of its parent item.
+	ForVariation bool
+}
+
+// contentType checks the upload's size and format and returns its detected content type. The
+// type the client sent is not trusted.
+func (u *ImageUpload) contentType() (string, error) {
+	if len(u.Data) == 0 {
+		return "", fmt.Errorf("%w: the file is empty", ErrInvalidImage)
+	}
+	if len(u.Data) > MaxImageSize {
+		return "", ErrImageTooLarge
+	}
+
+	contentType := http.DetectContentType(u.Data)
+	if !imageContentTypes[contentType] {
+		return "", fmt.Errorf("%w: only JPEG, PNG and GIF files are accepted", ErrInvalidImage)
+	}
+
+	return contentType, nil
+}
+
+// UploadInventoryItemImage uploads the image to Square as a catalog image attached to the item
+// or variation, and returns the item as it reads afterwards.
+func (b *SquareBackend) UploadInventoryItemImage(ctx context.Context, locationID, sku string, upload *ImageUpload) (*models.InventoryItem, error) {
+	contentType, err := upload.contentType()
+	if err != nil {
+		return nil, err
+	}
+
+	locationID, err = b.resolveWriteLocation(ctx, locationID)
+	if err != nil {
+		return nil, err
+	}
+
+	idx, variationID, err := b.resolveSKU(ctx, sku)
+	if err != nil {
+		return nil, err
+	}
+
+	audit.Note(ctx, sku, func(record *models.AuditRecord) {
+		record.VariationID, record.LocationID = variationID, locationID
+	})
+
+	objectID := idx.variationDetails[variationID].itemID
+	if upload.ForVariation || objectID == "" {
+		objectID = variationID
+	}
+
+	image, err := createCatalogImage(ctx, objectID, variationID, upload, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Uploaded image %s for catalog object %s", image.GetImage().ID, objectID)
+
+	// Square attached the image by changing the object, so re-read it for the new image list.
+	target, err := fetchCatalogObject(ctx, objectID)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := []*square.CatalogObject{image, target}
+	b.catalog.apply(updated)
+	idx = idx.withObjects(updated)
+
+	qty, version, err := fetchInventoryCount(ctx, locationID, variationID)
+	if err != nil {
+		return nil, err
+	}
+
+	item := idx.inventoryItem(variationID, locationID, qty)
+	item.Version = version
+	return &item, nil
+}
+
+func createCatalogImage(ctx context.Context, objectID, variationID string, upload *ImageUpload, contentType string) (*square.CatalogObject, error) {
+	sqClient := client.SquareClient
+	if sqClient == nil {
+		return nil, errSquareClientNotInitialized
+	}
+
+	idempotencyKey := uuid.NewString()
+	audit.NoteIdempotencyKey(ctx, idempotencyKey, variationID)
+
+	imageResp, err := sqClient.Catalog.Images.Create(ctx, &catalog.CreateImagesRequest{
+		ImageFile: square.NewFileParam(bytes.NewReader(upload.Data), upload.Filename, contentType),
+		Request: &square.CreateCatalogImageRequest{
+			IdempotencyKey: idempotencyKey,
+			ObjectID:       square.String(objectID),
+			Image: &square.CatalogObject{
+				Type: "IMAGE",
+				Image: &square.CatalogObjectImage{
+					ID:        "#image",
+					ImageData: &square.CatalogImage{Name: square.String(upload.Filename)},
+				},
+			},
+			IsPrimary: square.Bool(upload.Primary),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if imageResp.Image == nil || imageResp.Image.Image == nil {
+		return nil, errors.New("square did not return the uploaded image")
+	}
+
+	return imageResp.Image, nil
+}
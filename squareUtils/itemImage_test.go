@@ -0,0 +1,68 @@
+package squareUtils
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// pngHeader is enough of a PNG file for its content type to be detected.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func uploadImage(t *testing.T, backend InventoryBackend, sku string, upload ImageUpload) string {
+	t.Helper()
+
+	upload.Data = pngHeader
+	item, err := backend.UploadInventoryItemImage(context.Background(), "", sku, &upload)
+	if err != nil {
+		t.Fatalf("UploadInventoryItemImage(%s): %v", upload.Filename, err)
+	}
+	if !strings.HasSuffix(item.ImageURL, ".png") {
+		t.Fatalf("image URL = %q, want the uploaded PNG", item.ImageURL)
+	}
+	return item.ImageURL
+}
+
+func TestUploadInventoryItemImage(t *testing.T) {
+	backend, _ := newFakeBackend(t)
+
+	first := uploadImage(t, backend, "LAT-001", ImageUpload{Filename: "latte.png"})
+	if got := getItem(t, backend, "LAT-001", "").ImageURL; got != first {
+		t.Errorf("image URL read back = %q, want %q", got, first)
+	}
+
+	// Another image is only shown first when it is primary.
+	uploadImage(t, backend, "LAT-001", ImageUpload{Filename: "side.png"})
+	if got := getItem(t, backend, "LAT-001", "").ImageURL; got != first {
+		t.Errorf("image URL after a second image = %q, want the first %q", got, first)
+	}
+	primary := uploadImage(t, backend, "LAT-001", ImageUpload{Filename: "new.png", Primary: true})
+	if got := getItem(t, backend, "LAT-001", "").ImageURL; got != primary {
+		t.Errorf("image URL after a primary image = %q, want %q", got, primary)
+	}
+}
+
+func TestUploadInventoryItemImageForVariation(t *testing.T) {
+	backend, _ := newFakeBackend(t)
+
+	uploadImage(t, backend, "LAT-001", ImageUpload{Filename: "item.png"})
+	variationURL := uploadImage(t, backend, "LAT-001", ImageUpload{Filename: "variation.png", ForVariation: true})
+
+	// The variation's own image wins over its item's.
+	if got := getItem(t, backend, "LAT-001", "").ImageURL; got != variationURL {
+		t.Errorf("image URL = %q, want the variation's %q", got, variationURL)
+	}
+}
+
+func TestUploadInventoryItemImageRejectsNonImages(t *testing.T) {
+	backend, _ := newFakeBackend(t)
+
+	upload := &ImageUpload{Filename: "notes.png", Data: []byte("not an image at all")}
+	if _, err := backend.UploadInventoryItemImage(context.Background(), "", "LAT-001", upload); !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("err = %v, want ErrInvalidImage", err)
+	}
+	if got := getItem(t, backend, "LAT-001", "").ImageURL; got != "" {
+		t.Errorf("image URL = %q, want none", got)
+	}
+}
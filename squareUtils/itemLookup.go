@@ -0,0 +1,150 @@
+package squareUtils
+
+import (
+	"aoa-inventory/squareUtils/client"
+	"context"
+	"errors"
+	"net/http"
+
+	square "github.com/square/square-go-sdk"
+	"github.com/square/square-go-sdk/catalog"
+	"github.com/square/square-go-sdk/core"
+)
+
+// searchVariationBySKU finds the variation with the given SKU, returning it with its parent
+// item. Variations without a SKU are listed under their ID, so those are looked up by ID.
+func searchVariationBySKU(ctx context.Context, sku string) (*square.CatalogObject, []*square.CatalogObject, error) {
+	sqClient := client.SquareClient
+	if sqClient == nil {
+		return nil, nil, errSquareClientNotInitialized
+	}
+
+	searchResp, err := sqClient.Catalog.Search(ctx, &square.SearchCatalogObjectsRequest{
+		ObjectTypes:           []square.CatalogObjectType{square.CatalogObjectTypeItemVariation},
+		IncludeRelatedObjects: square.Bool(true),
+		Query: &square.CatalogQuery{
+			ExactQuery: &square.CatalogQueryExact{AttributeName: "sku", AttributeValue: sku},
+		},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Square matches the value ignoring case, so only an identical SKU counts.
+	for _, obj := range searchResp.Objects {
+		if variationSKU(obj) == sku {
+			return obj, searchResp.RelatedObjects, nil
+		}
+	}
+
+	variation, related, err := fetchVariation(ctx, sku)
+	if err != nil {
+		return nil, nil, err
+	}
+	if variationSKU(variation) != "" {
+		return nil, nil, ErrInventoryItemNotFound
+	}
+	return variation, related, nil
+}
+
+// fetchVariation retrieves the variation with the given ID, returning it with its parent item.
+func fetchVariation(ctx context.Context, variationID string) (*square.CatalogObject, []*square.CatalogObject, error) {
+	sqClient := client.SquareClient
+	if sqClient == nil {
+		return nil, nil, errSquareClientNotInitialized
+	}
+
+	objectResp, err := sqClient.Catalog.Object.Get(ctx, &catalog.GetObjectRequest{
+		ObjectID:              variationID,
+		IncludeRelatedObjects: square.Bool(true),
+	})
+	if err != nil {
+		var apiErr *core.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			return nil, nil, ErrInventoryItemNotFound
+		}
+		return nil, nil, err
+	}
+
+	if objectResp.Object == nil || objectResp.Object.ItemVariation == nil {
+		return nil, nil, ErrInventoryItemNotFound
+	}
+
+	return objectResp.Object, objectResp.RelatedObjects, nil
+}
+
+// variationIndex indexes a variation with its parent item and the categories, images and item
+// options they reference, fetching those in one request. It is enough to build the variation's
+// inventory item without loading the whole catalog.
+func variationIndex(ctx context.Context, variation *square.CatalogObject, related []*square.CatalogObject) (*catalogIndex, error) {
+	sqClient := client.SquareClient
+	if sqClient == nil {
+		return nil, errSquareClientNotInitialized
+	}
+
+	objects := append([]*square.CatalogObject{}, related...)
+
+	referenced := referencedObjectIDs(variation, related)
+	if len(referenced) > 0 {
+		batchResp, err := sqClient.Catalog.BatchGet(ctx, &square.BatchGetCatalogObjectsRequest{ObjectIDs: referenced})
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, batchResp.Objects...)
+	}
+
+	// The variation goes last so a copy embedded in its item does not replace it.
+	return newCatalogIndex(append(objects, variation)), nil
+}
+
+// referencedObjectIDs returns the IDs of the categories, images and item options the variation
+// and its parent item point to.
+func referencedObjectIDs(variation *square.CatalogObject, related []*square.CatalogObject) []string {
+	ids := []string{}
+	seen := map[string]bool{}
+	addID := func(id *string) {
+		if id != nil && *id != "" && !seen[*id] {
+			seen[*id] = true
+			ids = append(ids, *id)
+		}
+	}
+
+	vData := variation.ItemVariation.ItemVariationData
+	if vData == nil {
+		return ids
+	}
+
+	addID(variation.ItemVariation.ImageID)
+	for i := range vData.ImageIDs {
+		addID(&vData.ImageIDs[i])
+	}
+	for _, option := range vData.ItemOptionValues {
+		if option != nil {
+			addID(option.ItemOptionID)
+		}
+	}
+
+	for _, obj := range related {
+		if obj == nil || obj.Item == nil || obj.Item.ItemData == nil || vData.ItemID == nil || obj.Item.ID != *vData.ItemID {
+			continue
+		}
+
+		itemData := obj.Item.ItemData
+		addID(itemData.CategoryID)
+		if itemData.ReportingCategory != nil {
+			addID(itemData.ReportingCategory.ID)
+		}
+		for i := range itemData.ImageIDs {
+			addID(&itemData.ImageIDs[i])
+		}
+	}
+
+	return ids
+}
+
+func variationSKU(obj *square.CatalogObject) string {
+	if obj == nil || obj.ItemVariation == nil || obj.ItemVariation.ItemVariationData == nil || obj.ItemVariation.ItemVariationData.Sku == nil {
+		return ""
+	}
+	return *obj.ItemVariation.ItemVariationData.Sku
+}
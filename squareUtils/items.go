@@ -0,0 +1,103 @@
+package squareUtils
+
+import (
+	"aoa-inventory/squareUtils/models"
+	"cmp"
+	"context"
+	"slices"
+)
+
+// ListItems returns the catalog items with their variations nested. Unlike ListInventory, it
+// includes variations that have no stock count yet.
+func (b *SquareBackend) ListItems(ctx context.Context, query InventoryQuery) ([]models.Item, error) {
+	locationIDs, err := b.resolveLocations(ctx, query.LocationID)
+	if err != nil {
+		return nil, err
+	}
+
+	variationCounts, err := fetchInventoryCounts(ctx, locationIDs, nil, countStates(query))
+	if err != nil {
+		log.Printf("ERROR: Failed to fetch inventory from Square: %v", err)
+		return nil, err
+	}
+
+	idx, err := b.catalog.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []models.Item{}
+	for itemID, variationIDs := range idx.variationsByItem() {
+		if _, ok := idx.itemsMeta[itemID]; !ok {
+			continue
+		}
+
+		item := idx.item(itemID)
+		if item.Archived && !query.IncludeArchived {
+			continue
+		}
+
+		for _, variationID := range variationIDs {
+			stockItem := b.inventoryItem(idx, variationID, query, locationIDs, variationCounts[variationID])
+			variation := variationFromInventoryItem(stockItem)
+			variation.Options = idx.optionValues(variationID)
+			item.Variations = append(item.Variations, variation)
+		}
+
+		items = append(items, item)
+	}
+
+	sortItems(items)
+
+	log.Printf("Loaded %d catalog items from Square", len(items))
+
+	return items, nil
+}
+
+// ListItems returns every item in the file as an item with a single variation, since the file
+// does not record which rows belong together.
+func (b *FileBackend) ListItems(ctx context.Context, query InventoryQuery) ([]models.Item, error) {
+	inventory, err := b.ListInventory(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []models.Item{}
+	for _, stockItem := range inventory {
+		items = append(items, models.Item{
+			ID:                stockItem.ID,
+			Name:              stockItem.Name,
+			Description:       stockItem.Description,
+			ImageURL:          stockItem.ImageURL,
+			Category:          stockItem.Category,
+			ReportingCategory: stockItem.ReportingCategory,
+			Archived:          stockItem.Archived,
+			Variations:        []models.Variation{variationFromInventoryItem(stockItem)},
+		})
+	}
+
+	sortItems(items)
+
+	return items, nil
+}
+
+func variationFromInventoryItem(stockItem models.InventoryItem) models.Variation {
+	return models.Variation{
+		ID:             stockItem.ID,
+		Name:           stockItem.VariationName,
+		SKU:            stockItem.SKU,
+		CurrentStock:   stockItem.CurrentStock,
+		ImageURL:       stockItem.ImageURL,
+		Price:          stockItem.Price,
+		InventoryAlert: stockItem.InventoryAlert,
+		Locations:      stockItem.Locations,
+		States:         stockItem.States,
+		Version:        stockItem.Version,
+	}
+}
+
+func sortItems(items []models.Item) {
+	slices.SortFunc(items, func(a, b models.Item) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.ID, b.ID))
+	})
+}
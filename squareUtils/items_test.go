@@ -0,0 +1,68 @@
+package squareUtils
+
+import (
+	"aoa-inventory/squareUtils/models"
+	"context"
+	"testing"
+)
+
+func TestListItemsNestsVariations(t *testing.T) {
+	backend, server := newFakeBackend(t)
+	ctx := context.Background()
+	server.AddLocation("LOC_SECOND", "Second Shop")
+
+	_, err := backend.CreateInventoryItem(ctx, "", &models.NewInventoryItem{
+		Name:     "Canvas Tote",
+		Category: "Merch",
+		Variations: []models.NewVariation{
+			{Name: "Small", SKU: "TOTE-S", CurrentStock: 3},
+			{Name: "Large", SKU: "TOTE-L", CurrentStock: 6},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateInventoryItem: %v", err)
+	}
+	if err := backend.DeleteInventoryItem(ctx, "BAK-002", false); err != nil {
+		t.Fatalf("DeleteInventoryItem: %v", err)
+	}
+
+	items, err := backend.ListItems(ctx, InventoryQuery{})
+	if err != nil {
+		t.Fatalf("ListItems: %v", err)
+	}
+
+	// The archived croissant is left out, and the rest are sorted by name.
+	if len(items) != 2 || items[0].Name != "Canvas Tote" || items[1].Name != "Vanilla Latte" {
+		t.Fatalf("items = %+v, want Canvas Tote and Vanilla Latte", items)
+	}
+
+	tote := items[0]
+	if tote.Category != "Merch" || len(tote.Variations) != 2 {
+		t.Fatalf("tote = %+v, want two variations in Merch", tote)
+	}
+	// Variations keep the order they were created in.
+	for i, want := range []models.Variation{{Name: "Small", SKU: "TOTE-S", CurrentStock: 3}, {Name: "Large", SKU: "TOTE-L", CurrentStock: 6}} {
+		got := tote.Variations[i]
+		if got.Name != want.Name || got.SKU != want.SKU || got.CurrentStock != want.CurrentStock {
+			t.Errorf("variation %d = %s %s with %d, want %s %s with %d", i, got.Name, got.SKU, got.CurrentStock, want.Name, want.SKU, want.CurrentStock)
+		}
+	}
+
+	// Nothing was counted at the second location, yet every variation is listed there.
+	items, err = backend.ListItems(ctx, InventoryQuery{LocationID: "LOC_SECOND", IncludeArchived: true})
+	if err != nil {
+		t.Fatalf("ListItems at the second location: %v", err)
+	}
+	variations := 0
+	for _, item := range items {
+		for _, variation := range item.Variations {
+			variations++
+			if variation.CurrentStock != 0 {
+				t.Errorf("%s at the second location = %d, want 0", variation.SKU, variation.CurrentStock)
+			}
+		}
+	}
+	if variations != 4 {
+		t.Errorf("listed %d variations at the second location, want 4", variations)
+	}
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// AuditRecord is one write made through the API, as appended to the audit log.
+type AuditRecord struct {
+	Time        time.Time `json:"time"`
+	User        string    `json:"user"`
+	Action      string    `json:"action"`
+	SKU         string    `json:"sku"`
+	VariationID string    `json:"variationId,omitempty"`
+	LocationID  string    `json:"locationId,omitempty"`
+
+	// OldStock and NewStock are set when the write read or changed stock.
+	OldStock *int   `json:"oldStock,omitempty"`
+	NewStock *int   `json:"newStock,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+
+	// IdempotencyKey is the key of the last Square request made for the write.
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
@@ -0,0 +1,27 @@
+package models
+
+// InventoryChange is one entry in a SKU's stock history: an ADJUSTMENT between two states, a
+// PHYSICAL_COUNT that set the quantity in ToState, or a TRANSFER between locations.
+type InventoryChange struct {
+	ID             string `json:"id"`
+	Type           string `json:"type"`
+	OccurredAt     string `json:"occurredAt"`
+	Quantity       int    `json:"quantity"`
+	FromState      string `json:"fromState,omitempty"`
+	ToState        string `json:"toState,omitempty"`
+	LocationID     string `json:"locationId,omitempty"`
+	FromLocationID string `json:"fromLocationId,omitempty"`
+	ToLocationID   string `json:"toLocationId,omitempty"`
+
+	// Source names the application or Square product that made the change.
+	Source      string `json:"source,omitempty"`
+	ReferenceID string `json:"referenceId,omitempty"`
+}
+
+// InventoryHistory is one page of a SKU's stock history, oldest change first. Cursor fetches
+// the next page and is empty on the last one.
+type InventoryHistory struct {
+	SKU     string            `json:"sku"`
+	Changes []InventoryChange `json:"changes"`
+	Cursor  string            `json:"cursor,omitempty"`
+}
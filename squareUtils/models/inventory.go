@@ -1,14 +1,45 @@
 package models
 
+import "time"
+
 type InventoryItem struct {
 	ID                string `json:"id"`
 	Name              string `json:"name"`
+	VariationName     string `json:"variationName"`
 	Description       string `json:"description"`
 	SKU               string `json:"sku"`
 	CurrentStock      int    `json:"currentStock"`
 	ImageURL          string `json:"imageUrl"`
 	Category          string `json:"category"`
 	ReportingCategory string `json:"reportingCategory"`
+	Archived          bool   `json:"archived,omitempty"`
+
+	// Price is the variation's price at the location read, or its base price across all locations.
+	Price *Price `json:"price,omitempty"`
+
+	// InventoryAlert is the low-stock alert set for the variation at the location read.
+	InventoryAlert *InventoryAlert `json:"inventoryAlert,omitempty"`
+
+	// Locations breaks CurrentStock down per location when stock across all locations is requested.
+	Locations []LocationStock `json:"locations,omitempty"`
+
+	// States holds the quantity in each requested Square inventory state, e.g. IN_STOCK or WASTE.
+	States map[string]int `json:"states,omitempty"`
+
+	// Version identifies the stock count the item was read with. Sending it back as If-Match or
+	// expectedVersion makes an update fail if stock changed in the meantime.
+	Version string `json:"version,omitempty"`
+
+	// CatalogVersion is the version of the item's catalog entry. Sending it back as
+	// expectedCatalogVersion makes a catalog update fail if the item was edited in the meantime.
+	CatalogVersion int64 `json:"catalogVersion,omitempty"`
+}
+
+// InventoryAlert is the low-stock alert Square raises for a variation at a location. Type is
+// NONE or LOW_QUANTITY; with LOW_QUANTITY, stock at or below Threshold counts as low.
+type InventoryAlert struct {
+	Type      string `json:"type"`
+	Threshold int    `json:"threshold"`
 }
 
 // InventoryItemUpdate represents optional updates for an inventory item.
@@ -20,6 +51,53 @@ type InventoryItemUpdate struct {
 	ImageURL          *string `json:"imageUrl"`
 	Category          *string `json:"category"`
 	ReportingCategory *string `json:"reportingCategory"`
+	Archived          *bool   `json:"archived"`
+
+	Price          *PriceUpdate    `json:"price"`
+	InventoryAlert *InventoryAlert `json:"inventoryAlert"`
+
+	// Reason explains a CurrentStock change, e.g. "received" or "waste". It is not stored on the item.
+	// Without one, CurrentStock is recorded as a physical count taken at CountedAt.
+	Reason    *string    `json:"reason"`
+	CountedAt *time.Time `json:"countedAt"`
+
+	// ExpectedCurrentStock and ExpectedVersion make the update fail with a conflict if the
+	// item's stock no longer matches them.
+	ExpectedCurrentStock *int    `json:"expectedCurrentStock"`
+	ExpectedVersion      *string `json:"expectedVersion"`
+
+	// ExpectedCatalogVersion makes an update of catalog fields fail with a conflict if the
+	// item's catalog entry is no longer at this version.
+	ExpectedCatalogVersion *int64 `json:"expectedCatalogVersion"`
+}
+
+// NewInventoryItem describes an item to create. Without Variations, the item gets a single
+// variation from SKU, Price and CurrentStock.
+type NewInventoryItem struct {
+	Name              string         `json:"name"`
+	Description       string         `json:"description"`
+	Category          string         `json:"category"`
+	ReportingCategory string         `json:"reportingCategory"`
+	SKU               string         `json:"sku"`
+	Price             *Money         `json:"price"`
+	CurrentStock      int            `json:"currentStock"`
+	Variations        []NewVariation `json:"variations"`
+}
+
+// NewVariation is one sellable variation of a new item, e.g. a size. CurrentStock is the
+// opening count.
+type NewVariation struct {
+	Name         string `json:"name"`
+	SKU          string `json:"sku"`
+	Price        *Money `json:"price"`
+	CurrentStock int    `json:"currentStock"`
+}
+
+// InventoryAdjustment is a signed change to an item's stock, applied without reading the
+// current count first.
+type InventoryAdjustment struct {
+	Delta  *int    `json:"delta"`
+	Reason *string `json:"reason"`
 }
 
 // ApplyUpdate merges provided fields onto an InventoryItem without overwriting missing values.
@@ -55,4 +133,38 @@ func (i *InventoryItem) ApplyUpdate(update *InventoryItemUpdate) {
 	if update.ReportingCategory != nil {
 		i.ReportingCategory = *update.ReportingCategory
 	}
+
+	if update.Archived != nil {
+		i.Archived = *update.Archived
+	}
+
+	if update.InventoryAlert != nil {
+		alert := *update.InventoryAlert
+		i.InventoryAlert = &alert
+	}
+
+	if update.Price != nil {
+		i.Price = &Price{Amount: update.Price.Amount, Currency: update.Price.Currency, PricingType: "FIXED_PRICING"}
+		if update.Price.Amount == nil {
+			i.Price = &Price{PricingType: "VARIABLE_PRICING"}
+		}
+	}
+}
+
+// StockChange is one entry of a bulk stock update. It sets either an absolute CurrentStock or a
+// signed Delta, optionally with a reason such as "received".
+type StockChange struct {
+	SKU          string  `json:"sku"`
+	CurrentStock *int    `json:"currentStock"`
+	Delta        *int    `json:"delta"`
+	Reason       *string `json:"reason"`
+}
+
+// StockChangeResult reports what happened to one entry of a bulk stock update. Status is the
+// HTTP status the change would have had on its own.
+type StockChangeResult struct {
+	SKU    string         `json:"sku"`
+	Status int            `json:"status"`
+	Item   *InventoryItem `json:"item,omitempty"`
+	Error  string         `json:"error,omitempty"`
 }
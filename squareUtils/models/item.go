@@ -0,0 +1,35 @@
+package models
+
+// Item is a catalog item with its variations nested, e.g. a mug with a small and a large size.
+type Item struct {
+	ID                string      `json:"id"`
+	Name              string      `json:"name"`
+	Description       string      `json:"description"`
+	ImageURL          string      `json:"imageUrl"`
+	Category          string      `json:"category"`
+	ReportingCategory string      `json:"reportingCategory"`
+	Archived          bool        `json:"archived,omitempty"`
+	Variations        []Variation `json:"variations"`
+}
+
+// Variation is one sellable variation of an Item and its stock.
+type Variation struct {
+	ID             string          `json:"id"`
+	Name           string          `json:"name"`
+	SKU            string          `json:"sku"`
+	CurrentStock   int             `json:"currentStock"`
+	ImageURL       string          `json:"imageUrl"`
+	Price          *Price          `json:"price,omitempty"`
+	InventoryAlert *InventoryAlert `json:"inventoryAlert,omitempty"`
+	Options        []OptionValue   `json:"options,omitempty"`
+
+	Locations []LocationStock `json:"locations,omitempty"`
+	States    map[string]int  `json:"states,omitempty"`
+	Version   string          `json:"version,omitempty"`
+}
+
+// OptionValue is the value an item option takes for a variation, e.g. Size: Large.
+type OptionValue struct {
+	Option string `json:"option"`
+	Value  string `json:"value"`
+}
@@ -0,0 +1,17 @@
+package models
+
+// Location is a business location inventory can be tracked at.
+type Location struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Currency  string `json:"currency,omitempty"`
+	IsDefault bool   `json:"isDefault"`
+}
+
+// LocationStock is the stock of an inventory item at a single location.
+type LocationStock struct {
+	LocationID   string         `json:"locationId"`
+	CurrentStock int            `json:"currentStock"`
+	States       map[string]int `json:"states,omitempty"`
+}
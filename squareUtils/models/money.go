@@ -0,0 +1,23 @@
+package models
+
+// Money is an amount in the smallest unit of its ISO 4217 currency, e.g. cents for USD.
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// Price is what a variation sells for. Variable-priced variations have no amount; the price is
+// entered at the point of sale.
+type Price struct {
+	Amount      *int64 `json:"amount,omitempty"`
+	Currency    string `json:"currency,omitempty"`
+	PricingType string `json:"pricingType"`
+}
+
+// PriceUpdate sets a variation's price. With AtLocation it only applies at the update's location,
+// as a location override. A nil Amount switches to variable pricing, or removes the override.
+type PriceUpdate struct {
+	Amount     *int64 `json:"amount"`
+	Currency   string `json:"currency"`
+	AtLocation bool   `json:"atLocation"`
+}
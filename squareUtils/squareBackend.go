@@ -0,0 +1,464 @@
+package squareUtils
+
+import (
+	"aoa-inventory/audit"
+	"aoa-inventory/squareUtils/client"
+	"aoa-inventory/squareUtils/models"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	square "github.com/square/square-go-sdk"
+)
+
+// skuMissRefreshAge bounds how stale the catalog may be before an unknown SKU forces a reload,
+// so newly created items are found without letting bad SKUs trigger a reload on every request.
+const skuMissRefreshAge = 30 * time.Second
+
+// SquareBackend serves inventory from the Square catalog and inventory APIs.
+type SquareBackend struct {
+	locationID string
+	catalog    *CatalogCache
+
+	locationsMu        sync.Mutex
+	locations          []models.Location
+	locationsFetchedAt time.Time
+}
+
+func NewSquareBackend(locationID string, catalog *CatalogCache) *SquareBackend {
+	return &SquareBackend{locationID: locationID, catalog: catalog}
+}
+
+func (b *SquareBackend) ListInventory(ctx context.Context, query InventoryQuery) ([]models.InventoryItem, error) {
+	if client.SquareClient == nil {
+		return nil, errSquareClientNotInitialized
+	}
+
+	locationIDs, err := b.resolveLocations(ctx, query.LocationID)
+	if err != nil {
+		return nil, err
+	}
+
+	variationCounts, err := fetchInventoryCounts(ctx, locationIDs, nil, countStates(query))
+	if err != nil {
+		log.Printf("ERROR: Failed to fetch inventory from Square: %v", err)
+		return nil, err
+	}
+
+	idx, err := b.catalog.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []models.InventoryItem{}
+	missingFromCatalog := false
+	for variationID, locationCounts := range variationCounts {
+		if _, ok := idx.variationDetails[variationID]; !ok {
+			missingFromCatalog = true
+		} else if !query.IncludeArchived && idx.isArchived(variationID) {
+			continue
+		}
+		items = append(items, b.inventoryItem(idx, variationID, query, locationIDs, locationCounts))
+	}
+
+	if query.IncludeUncounted {
+		for variationID := range idx.variationDetails {
+			if _, ok := variationCounts[variationID]; ok || (!query.IncludeArchived && idx.isArchived(variationID)) {
+				continue
+			}
+			if slices.ContainsFunc(locationIDs, func(locationID string) bool { return idx.tracksInventory(variationID, locationID) }) {
+				items = append(items, b.inventoryItem(idx, variationID, query, locationIDs, nil))
+			}
+		}
+	}
+
+	// Counts for variations the cached catalog has never seen mean it is out of date.
+	if missingFromCatalog && b.catalog.age() >= skuMissRefreshAge {
+		b.catalog.refreshInBackground()
+	}
+
+	log.Printf("Loaded %d inventory items from Square", len(items))
+
+	SortInventory(items, SortByName, false)
+	return items, nil
+}
+
+func (b *SquareBackend) GetInventoryItem(ctx context.Context, sku string, query InventoryQuery) (*models.InventoryItem, error) {
+	if sku == "" {
+		return nil, errors.New("sku is required")
+	}
+
+	locationIDs, err := b.resolveLocations(ctx, query.LocationID)
+	if err != nil {
+		return nil, err
+	}
+
+	variation, related, err := searchVariationBySKU(ctx, sku)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.variationInventoryItem(ctx, variation, related, query, locationIDs)
+}
+
+func (b *SquareBackend) GetInventoryItemByVariationID(ctx context.Context, variationID string, query InventoryQuery) (*models.InventoryItem, error) {
+	if variationID == "" {
+		return nil, errors.New("variation ID is required")
+	}
+
+	locationIDs, err := b.resolveLocations(ctx, query.LocationID)
+	if err != nil {
+		return nil, err
+	}
+
+	variation, related, err := fetchVariation(ctx, variationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.variationInventoryItem(ctx, variation, related, query, locationIDs)
+}
+
+// variationInventoryItem builds the inventory item for one variation from its own catalog
+// objects and counts, rather than the cached catalog, so a single item is always current.
+func (b *SquareBackend) variationInventoryItem(ctx context.Context, variation *square.CatalogObject, related []*square.CatalogObject, query InventoryQuery, locationIDs []string) (*models.InventoryItem, error) {
+	idx, err := variationIndex(ctx, variation, related)
+	if err != nil {
+		return nil, err
+	}
+
+	variationID := variation.ItemVariation.ID
+	variationCounts, err := fetchInventoryCounts(ctx, locationIDs, []string{variationID}, countStates(query))
+	if err != nil {
+		return nil, err
+	}
+
+	item := b.inventoryItem(idx, variationID, query, locationIDs, variationCounts[variationID])
+	return &item, nil
+}
+
+func (b *SquareBackend) UpdateInventoryItem(ctx context.Context, locationID, sku string, update *models.InventoryItemUpdate) (*models.InventoryItem, error) {
+	if update == nil {
+		return nil, errors.New("update is required")
+	}
+
+	if sku == "" {
+		return nil, errors.New("sku is required")
+	}
+
+	if update.CurrentStock == nil && !HasCatalogFields(update) {
+		return nil, fmt.Errorf("%w: nothing to update", ErrInvalidItemUpdate)
+	}
+
+	reason, countedAt, err := parseStockUpdate(update)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkPriceUpdate(update.Price); err != nil {
+		return nil, err
+	}
+	if update.InventoryAlert, err = normalizeInventoryAlert(update.InventoryAlert); err != nil {
+		return nil, err
+	}
+
+	locationID, err = b.resolveWriteLocation(ctx, locationID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use the cached catalog to map SKU -> variation and enrich response.
+	idx, variationID, err := b.resolveSKU(ctx, sku)
+	if err != nil {
+		return nil, err
+	}
+
+	audit.Note(ctx, sku, func(record *models.AuditRecord) {
+		record.VariationID, record.LocationID, record.Reason = variationID, locationID, string(reason)
+	})
+
+	// The current count is needed to check preconditions, to turn a reason-coded update into a
+	// delta, to report stock after a catalog-only update and for the audit log. A physical count
+	// records the new quantity as is.
+	currentQty, currentVersion, err := fetchInventoryCount(ctx, locationID, variationID)
+	if err != nil {
+		return nil, err
+	}
+	audit.Note(ctx, sku, func(record *models.AuditRecord) { record.OldStock = &currentQty })
+
+	current := idx.inventoryItem(variationID, locationID, currentQty)
+	current.Version = currentVersion
+
+	// Square cannot make the change conditional, so this only narrows the window in which a
+	// concurrent change can slip between the read and the write.
+	if err := checkExpectedStock(update, current); err != nil {
+		return nil, err
+	}
+
+	if HasCatalogFields(update) {
+		idx, err = b.updateCatalogItem(ctx, idx, locationID, variationID, update)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	newQty, newVersion := current.CurrentStock, current.Version
+	switch {
+	case update.CurrentStock == nil:
+		// Catalog-only update; stock is unchanged.
+	case reason == "":
+		newQty, newVersion, err = recordPhysicalCount(ctx, locationID, variationID, *update.CurrentStock, countedAt)
+	case *update.CurrentStock != current.CurrentStock:
+		newQty, newVersion, err = adjustInventoryCount(ctx, locationID, variationID, *update.CurrentStock-current.CurrentStock, reason)
+	}
+	if err != nil {
+		return nil, err
+	}
+	audit.Note(ctx, sku, func(record *models.AuditRecord) { record.NewStock = &newQty })
+
+	// Return the updated item with new stock.
+	item := idx.inventoryItem(variationID, locationID, newQty)
+	item.Version = newVersion
+	return &item, nil
+}
+
+func (b *SquareBackend) AdjustInventoryItem(ctx context.Context, locationID, sku string, delta int, reason AdjustmentReason) (*models.InventoryItem, error) {
+	if sku == "" {
+		return nil, errors.New("sku is required")
+	}
+
+	if err := reason.checkAdjustment(delta); err != nil {
+		return nil, err
+	}
+
+	locationID, err := b.resolveWriteLocation(ctx, locationID)
+	if err != nil {
+		return nil, err
+	}
+
+	idx, variationID, err := b.resolveSKU(ctx, sku)
+	if err != nil {
+		return nil, err
+	}
+
+	audit.Note(ctx, sku, func(record *models.AuditRecord) {
+		record.VariationID, record.LocationID, record.Reason = variationID, locationID, string(reason)
+	})
+
+	newQty, newVersion, err := adjustInventoryCount(ctx, locationID, variationID, delta, reason)
+	if err != nil {
+		return nil, err
+	}
+	// Square applied the delta to whatever the count was, so the old count follows from the new.
+	oldQty := newQty - delta
+	audit.Note(ctx, sku, func(record *models.AuditRecord) { record.OldStock, record.NewStock = &oldQty, &newQty })
+
+	item := idx.inventoryItem(variationID, locationID, newQty)
+	item.Version = newVersion
+	return &item, nil
+}
+
+func (b *SquareBackend) ListLocations(ctx context.Context) ([]models.Location, error) {
+	return b.cachedLocations(ctx, 0)
+}
+
+// inventoryItem builds the API model from the counts of a variation at the queried locations.
+func (b *SquareBackend) inventoryItem(idx *catalogIndex, variationID string, query InventoryQuery, locationIDs []string, locationCounts map[string]*locationCount) models.InventoryItem {
+	if query.LocationID != AllLocations {
+		counts := locationCounts[locationIDs[0]]
+		if counts == nil {
+			counts = &locationCount{}
+		}
+
+		stock, version := counts.inStock()
+		item := idx.inventoryItem(variationID, locationIDs[0], stock)
+		item.Version = version
+		item.States = stateBreakdown(query, counts.states)
+		return item
+	}
+
+	total := 0
+	totals := stateCounts{}
+	breakdown := []models.LocationStock{}
+	for _, locationID := range locationIDs {
+		counts, ok := locationCounts[locationID]
+		if !ok {
+			continue
+		}
+
+		stock, _ := counts.inStock()
+		total += stock
+		for state, qty := range counts.states {
+			totals[state] += qty
+		}
+
+		breakdown = append(breakdown, models.LocationStock{
+			LocationID:   locationID,
+			CurrentStock: stock,
+			States:       stateBreakdown(query, counts.states),
+		})
+	}
+
+	item := idx.inventoryItem(variationID, "", total)
+	item.Locations = breakdown
+	item.States = stateBreakdown(query, totals)
+	return item
+}
+
+// countStates returns the inventory states to fetch counts for. IN_STOCK is always fetched
+// because it backs CurrentStock.
+func countStates(query InventoryQuery) []square.InventoryState {
+	states := []square.InventoryState{square.InventoryStateInStock}
+	for _, state := range query.States {
+		if state != string(square.InventoryStateInStock) {
+			states = append(states, square.InventoryState(state))
+		}
+	}
+	return states
+}
+
+// stateBreakdown reports every requested state, including those with no count.
+func stateBreakdown(query InventoryQuery, counts stateCounts) map[string]int {
+	if len(query.States) == 0 {
+		return nil
+	}
+
+	breakdown := map[string]int{}
+	for _, state := range query.States {
+		breakdown[state] = counts[square.InventoryState(state)]
+	}
+	return breakdown
+}
+
+// resolveSKU finds the variation ID for the given SKU in the cached catalog, reloading
+// the catalog once if the SKU is unknown and the cached copy is not recent.
+func (b *SquareBackend) resolveSKU(ctx context.Context, sku string) (*catalogIndex, string, error) {
+	idx, err := b.catalog.get(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if variationID, ok := idx.variationIDForSKU(sku); ok {
+		return idx, variationID, nil
+	}
+
+	idx, err = b.catalog.getFresh(ctx, skuMissRefreshAge)
+	if err != nil {
+		return nil, "", err
+	}
+
+	variationID, ok := idx.variationIDForSKU(sku)
+	if !ok {
+		return nil, "", ErrInventoryItemNotFound
+	}
+
+	return idx, variationID, nil
+}
+
+// resolveLocations turns a requested location into the location IDs to read counts for.
+func (b *SquareBackend) resolveLocations(ctx context.Context, locationID string) ([]string, error) {
+	if locationID == "" || locationID == b.locationID {
+		return []string{b.locationID}, nil
+	}
+
+	locations, err := b.cachedLocations(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if locationID == AllLocations {
+		locationIDs := []string{}
+		for _, location := range locations {
+			if location.Status == string(square.LocationStatusActive) {
+				locationIDs = append(locationIDs, location.ID)
+			}
+		}
+		if len(locationIDs) == 0 {
+			return nil, ErrLocationNotFound
+		}
+		return locationIDs, nil
+	}
+
+	if !hasLocation(locations, locationID) {
+		// The location may have been added since the list was cached.
+		locations, err = b.cachedLocations(ctx, skuMissRefreshAge)
+		if err != nil {
+			return nil, err
+		}
+		if !hasLocation(locations, locationID) {
+			return nil, ErrLocationNotFound
+		}
+	}
+
+	return []string{locationID}, nil
+}
+
+// resolveWriteLocation validates the single location a stock change is made at.
+func (b *SquareBackend) resolveWriteLocation(ctx context.Context, locationID string) (string, error) {
+	if locationID == AllLocations {
+		return "", ErrLocationNotFound
+	}
+
+	locationIDs, err := b.resolveLocations(ctx, locationID)
+	if err != nil {
+		return "", err
+	}
+
+	return locationIDs[0], nil
+}
+
+// cachedLocations returns the merchant's locations, reloading them when the cached list is
+// older than maxAge. A zero maxAge only loads the list if it has never been loaded.
+func (b *SquareBackend) cachedLocations(ctx context.Context, maxAge time.Duration) ([]models.Location, error) {
+	b.locationsMu.Lock()
+	defer b.locationsMu.Unlock()
+
+	if b.locations != nil && (maxAge == 0 || time.Since(b.locationsFetchedAt) < maxAge) {
+		return b.locations, nil
+	}
+
+	squareLocations, err := fetchLocations(ctx)
+	if err != nil {
+		log.Printf("ERROR: Failed to fetch locations from Square: %v", err)
+		return nil, err
+	}
+
+	locations := []models.Location{}
+	for _, location := range squareLocations {
+		if location == nil || location.ID == nil {
+			continue
+		}
+
+		converted := models.Location{
+			ID:        *location.ID,
+			IsDefault: *location.ID == b.locationID,
+		}
+		if location.Name != nil {
+			converted.Name = *location.Name
+		}
+		if location.Status != nil {
+			converted.Status = string(*location.Status)
+		}
+		if location.Currency != nil {
+			converted.Currency = string(*location.Currency)
+		}
+		locations = append(locations, converted)
+	}
+
+	b.locations = locations
+	b.locationsFetchedAt = time.Now()
+
+	return locations, nil
+}
+
+func hasLocation(locations []models.Location, locationID string) bool {
+	for _, location := range locations {
+		if location.ID == locationID {
+			return true
+		}
+	}
+	return false
+}
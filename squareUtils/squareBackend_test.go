@@ -0,0 +1,123 @@
+package squareUtils
+
+import (
+	"aoa-inventory/squareUtils/models"
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"testing"
+
+	square "github.com/square/square-go-sdk"
+)
+
+func TestUpdateInventoryItemUnknownSKU(t *testing.T) {
+	backend, _ := newFakeBackend(t)
+
+	_, err := backend.UpdateInventoryItem(context.Background(), "", "NOPE", &models.InventoryItemUpdate{CurrentStock: ptr(1)})
+	if !errors.Is(err, ErrInventoryItemNotFound) {
+		t.Errorf("err = %v, want ErrInventoryItemNotFound", err)
+	}
+}
+
+func TestAdjustInventoryItemSquareError(t *testing.T) {
+	backend, server := newFakeBackend(t)
+	server.FailNext("/v2/inventory/changes/batch-create", http.StatusBadRequest, square.ErrorCodeBadRequest, "rejected")
+
+	if _, err := backend.AdjustInventoryItem(context.Background(), "", "LAT-001", 1, ""); err == nil {
+		t.Fatal("err = nil, want the Square error")
+	}
+	if got := getItem(t, backend, "LAT-001", "").CurrentStock; got != 10 {
+		t.Errorf("stock = %d, want it unchanged at 10", got)
+	}
+}
+
+func TestInventoryAcrossLocations(t *testing.T) {
+	backend, server := newFakeBackend(t)
+	ctx := context.Background()
+	server.AddLocation("LOC_SECOND", "Second Shop")
+
+	if _, err := backend.UpdateInventoryItem(ctx, "LOC_SECOND", "LAT-001", &models.InventoryItemUpdate{CurrentStock: ptr(4)}); err != nil {
+		t.Fatalf("UpdateInventoryItem at the second location: %v", err)
+	}
+
+	if got := getItem(t, backend, "LAT-001", "LOC_SECOND").CurrentStock; got != 4 {
+		t.Errorf("stock at the second location = %d, want 4", got)
+	}
+	if got := getItem(t, backend, "LAT-001", "").CurrentStock; got != 10 {
+		t.Errorf("stock at the default location = %d, want it unchanged at 10", got)
+	}
+
+	inventory, err := backend.ListInventory(ctx, InventoryQuery{LocationID: AllLocations})
+	if err != nil {
+		t.Fatalf("ListInventory: %v", err)
+	}
+
+	want := map[string]map[string]int{
+		"LAT-001": {server.LocationID: 10, "LOC_SECOND": 4},
+		"BAK-002": {server.LocationID: 4},
+	}
+	for _, item := range inventory {
+		total := 0
+		for _, stock := range want[item.SKU] {
+			total += stock
+		}
+		if item.CurrentStock != total {
+			t.Errorf("%s: stock across locations = %d, want %d", item.SKU, item.CurrentStock, total)
+		}
+
+		got := map[string]int{}
+		for _, location := range item.Locations {
+			got[location.LocationID] = location.CurrentStock
+		}
+		if !maps.Equal(got, want[item.SKU]) {
+			t.Errorf("%s: locations = %v, want %v", item.SKU, got, want[item.SKU])
+		}
+	}
+
+	if _, err := backend.GetInventoryItem(ctx, "LAT-001", InventoryQuery{LocationID: "LOC_NOPE"}); !errors.Is(err, ErrLocationNotFound) {
+		t.Errorf("unknown location: err = %v, want ErrLocationNotFound", err)
+	}
+}
+
+func TestInventoryStateBreakdown(t *testing.T) {
+	backend, _ := newFakeBackend(t)
+	ctx := context.Background()
+
+	if _, err := backend.AdjustInventoryItem(ctx, "", "LAT-001", -2, ReasonWaste); err != nil {
+		t.Fatalf("AdjustInventoryItem(waste): %v", err)
+	}
+	if _, err := backend.AdjustInventoryItem(ctx, "", "LAT-001", -1, ReasonSold); err != nil {
+		t.Fatalf("AdjustInventoryItem(sold): %v", err)
+	}
+
+	states, err := ParseInventoryStates("in_stock, WASTE,SOLD,waste")
+	if err != nil {
+		t.Fatalf("ParseInventoryStates: %v", err)
+	}
+	// Square keeps no count of sold items, but a requested state is always reported.
+	want := map[string]int{"IN_STOCK": 7, "WASTE": 2, "SOLD": 0}
+
+	item, err := backend.GetInventoryItem(ctx, "LAT-001", InventoryQuery{States: states})
+	if err != nil {
+		t.Fatalf("GetInventoryItem: %v", err)
+	}
+	if item.CurrentStock != 7 || !maps.Equal(item.States, want) {
+		t.Errorf("item = %d with states %v, want 7 with %v", item.CurrentStock, item.States, want)
+	}
+
+	item, err = backend.GetInventoryItem(ctx, "LAT-001", InventoryQuery{LocationID: AllLocations, States: states})
+	if err != nil {
+		t.Fatalf("GetInventoryItem across locations: %v", err)
+	}
+	if !maps.Equal(item.States, want) || len(item.Locations) != 1 || !maps.Equal(item.Locations[0].States, want) {
+		t.Errorf("states across locations = %v, per location %+v, want %v in both", item.States, item.Locations, want)
+	}
+
+	if item := getItem(t, backend, "LAT-001", ""); item.States != nil {
+		t.Errorf("states without asking = %v, want none", item.States)
+	}
+	if _, err := ParseInventoryStates("IN_STOCK,MISPLACED"); !errors.Is(err, ErrInvalidInventoryState) {
+		t.Errorf("unknown state: err = %v, want ErrInvalidInventoryState", err)
+	}
+}
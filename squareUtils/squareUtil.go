@@ -1,20 +1,20 @@
 package squareUtils
 
 import (
-	"aoa-inventory/config"
+	"aoa-inventory/audit"
 	"aoa-inventory/squareUtils/client"
-	"aoa-inventory/squareUtils/models"
 	"aoa-inventory/utils"
 	"context"
 	"encoding/json"
 	"errors"
 	"math"
-	"os"
+	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 	square "github.com/square/square-go-sdk"
+	"github.com/square/square-go-sdk/catalog"
 	"github.com/square/square-go-sdk/core"
 )
 
@@ -22,19 +22,53 @@ var log = utils.NewLogger("SQUARE-UTILS")
 
 var ErrInventoryItemNotFound = errors.New("inventory item not found")
 
-func fetchAllInventoryCounts(ctx context.Context) (map[string]int, error) {
+var errSquareClientNotInitialized = errors.New("square client is not initialized")
+
+// parseQuantity converts one of Square's decimal quantity strings to a whole stock count.
+func parseQuantity(quantity string) (int, error) {
+	qty, err := strconv.ParseFloat(quantity, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(math.Round(qty)), nil
+}
+
+// stateCounts holds the quantity of a variation at one location in each inventory state.
+type stateCounts map[square.InventoryState]int
+
+// locationCount is what Square reports for a variation at one location.
+type locationCount struct {
+	states stateCounts
+
+	// version identifies the IN_STOCK count and is empty if Square has none.
+	version string
+}
+
+// inStock returns the IN_STOCK quantity and its version, treating a missing count as zero.
+func (c *locationCount) inStock() (int, string) {
+	if c == nil || c.version == "" {
+		return 0, stockVersion(nil, 0)
+	}
+	return c.states[square.InventoryStateInStock], c.version
+}
+
+// fetchInventoryCounts returns counts keyed by variation ID and then location ID for the given
+// states. An empty variationIDs fetches every variation with a count at the given locations.
+func fetchInventoryCounts(ctx context.Context, locationIDs, variationIDs []string, states []square.InventoryState) (map[string]map[string]*locationCount, error) {
 	sqClient := client.SquareClient
 	if sqClient == nil {
-		return nil, errors.New("square client is not initialized")
+		return nil, errSquareClientNotInitialized
 	}
 
-	variationCounts := map[string]int{}
+	variationCounts := map[string]map[string]*locationCount{}
 	cursor := ""
 
 	for {
 		countReq := &square.BatchGetInventoryCountsRequest{
-			LocationIDs: []string{config.SquareLocationID},
-			States:      []square.InventoryState{square.InventoryStateInStock},
+			CatalogObjectIDs: variationIDs,
+			LocationIDs:      locationIDs,
+			States:           states,
 		}
 
 		if cursor != "" {
@@ -47,17 +81,30 @@ func fetchAllInventoryCounts(ctx context.Context) (map[string]int, error) {
 		}
 
 		for _, count := range countResp.Counts {
-			if count == nil || count.CatalogObjectID == nil || count.Quantity == nil {
+			if count == nil || count.CatalogObjectID == nil || count.LocationID == nil || count.State == nil || count.Quantity == nil {
 				continue
 			}
 
-			qty, err := strconv.ParseFloat(*count.Quantity, 64)
+			qty, err := parseQuantity(*count.Quantity)
 			if err != nil {
 				log.Printf("ERROR: Could not parse quantity for catalog object %s: %v", *count.CatalogObjectID, err)
 				continue
 			}
 
-			variationCounts[*count.CatalogObjectID] = int(math.Round(qty))
+			locationCounts := variationCounts[*count.CatalogObjectID]
+			if locationCounts == nil {
+				locationCounts = map[string]*locationCount{}
+				variationCounts[*count.CatalogObjectID] = locationCounts
+			}
+			counts := locationCounts[*count.LocationID]
+			if counts == nil {
+				counts = &locationCount{states: stateCounts{}}
+				locationCounts[*count.LocationID] = counts
+			}
+			counts.states[*count.State] = qty
+			if *count.State == square.InventoryStateInStock {
+				counts.version = stockVersion(count.CalculatedAt, qty)
+			}
 		}
 
 		if countResp.Cursor == nil || *countResp.Cursor == "" {
@@ -70,16 +117,42 @@ func fetchAllInventoryCounts(ctx context.Context) (map[string]int, error) {
 	return variationCounts, nil
 }
 
+// fetchInventoryCount returns the IN_STOCK count of a single variation at a location and its
+// version.
+func fetchInventoryCount(ctx context.Context, locationID, variationID string) (int, string, error) {
+	variationCounts, err := fetchInventoryCounts(ctx, []string{locationID}, []string{variationID}, []square.InventoryState{square.InventoryStateInStock})
+	if err != nil {
+		return 0, "", err
+	}
+
+	qty, version := variationCounts[variationID][locationID].inStock()
+	return qty, version, nil
+}
+
+func fetchLocations(ctx context.Context) ([]*square.Location, error) {
+	sqClient := client.SquareClient
+	if sqClient == nil {
+		return nil, errSquareClientNotInitialized
+	}
+
+	locationsResp, err := sqClient.Locations.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return locationsResp.Locations, nil
+}
+
 func fetchAllCatalogObjects(ctx context.Context) ([]*square.CatalogObject, error) {
 	sqClient := client.SquareClient
 	if sqClient == nil {
-		return nil, errors.New("square client is not initialized")
+		return nil, errSquareClientNotInitialized
 	}
 
 	catalogObjects := []*square.CatalogObject{}
 
 	listReq := &square.ListCatalogRequest{
-		Types: square.String("ITEM,ITEM_VARIATION,IMAGE,CATEGORY"),
+		Types: square.String("ITEM,ITEM_VARIATION,IMAGE,CATEGORY,ITEM_OPTION"),
 	}
 
 	page, err := sqClient.Catalog.List(ctx, listReq)
@@ -103,537 +176,251 @@ func fetchAllCatalogObjects(ctx context.Context) ([]*square.CatalogObject, error
 	}
 }
 
-func LoadSampleInventory(path string) []models.InventoryItem {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		log.Printf("Could not read %s: %v", path, err)
-		return []models.InventoryItem{}
-	}
-
-	items := []models.InventoryItem{}
-	if err := json.Unmarshal(data, &items); err != nil {
-		log.Printf("Could not parse %s: %v", path, err)
-		return []models.InventoryItem{}
-	}
-
-	return items
-}
-
-func LoadInventory() []models.InventoryItem {
-	if client.SquareClient == nil {
-		log.Println("ERROR: Square client is not initialized")
-		return []models.InventoryItem{}
-	}
-
-	ctx := context.Background()
-	items := []models.InventoryItem{}
-
-	variationCounts, err := fetchAllInventoryCounts(ctx)
-	if err != nil {
-		log.Printf("ERROR: Failed to fetch inventory from Square: %v", err)
-		return []models.InventoryItem{}
+// fetchCatalogObject returns a catalog object with its current version. Items include their
+// variations.
+func fetchCatalogObject(ctx context.Context, objectID string) (*square.CatalogObject, error) {
+	sqClient := client.SquareClient
+	if sqClient == nil {
+		return nil, errSquareClientNotInitialized
 	}
 
-	catalogObjects, err := fetchAllCatalogObjects(ctx)
+	objectResp, err := sqClient.Catalog.Object.Get(ctx, &catalog.GetObjectRequest{ObjectID: objectID})
 	if err != nil {
-		log.Printf("ERROR: Failed to fetch catalog items from Square: %v", err)
-		return []models.InventoryItem{}
-	}
-
-	type itemMeta struct {
-		name                  string
-		desc                  string
-		categoryID            string
-		categoryName          string
-		reportingCategoryID   string
-		reportingCategoryName string
-		imageIDs              []string
-	}
-
-	type variationMeta struct {
-		itemID        string
-		variationName string
-		sku           string
-		imageID       string
-	}
-
-	imageURLs := map[string]string{}
-	categoryNames := map[string]string{}
-	itemsMeta := map[string]itemMeta{}
-	variationDetails := map[string]variationMeta{}
-
-	// Build maps from the catalog objects we received.
-	for _, obj := range catalogObjects {
-		if obj == nil {
-			continue
+		var apiErr *core.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			return nil, ErrInventoryItemNotFound
 		}
-
-		switch obj.GetType() {
-		case "IMAGE":
-			if obj.Image != nil && obj.Image.ImageData != nil && obj.Image.ImageData.URL != nil {
-				imageURLs[obj.Image.ID] = *obj.Image.ImageData.URL
-			}
-		case "CATEGORY":
-			if obj.Category != nil && obj.Category.CategoryData != nil && obj.Category.CategoryData.Name != nil && obj.Category.ID != nil {
-				categoryNames[*obj.Category.ID] = *obj.Category.CategoryData.Name
-			}
-		case "ITEM":
-			if obj.Item != nil && obj.Item.ItemData != nil {
-				itemData := obj.Item.ItemData
-				meta := itemMeta{}
-				if itemData.Name != nil {
-					meta.name = *itemData.Name
-				}
-				if itemData.Description != nil {
-					meta.desc = *itemData.Description
-				}
-				if itemData.CategoryID != nil {
-					meta.categoryID = *itemData.CategoryID
-				} else if len(itemData.Categories) > 0 && itemData.Categories[0] != nil && itemData.Categories[0].CategoryData != nil && itemData.Categories[0].CategoryData.Name != nil {
-					if name := itemData.Categories[0].CategoryData.GetName(); name != nil {
-						meta.categoryName = *name
-					}
-				}
-				if itemData.ReportingCategory != nil {
-					if itemData.ReportingCategory.ID != nil {
-						meta.reportingCategoryID = *itemData.ReportingCategory.ID
-					}
-					if itemData.ReportingCategory.CategoryData != nil && itemData.ReportingCategory.CategoryData.Name != nil {
-						meta.reportingCategoryName = *itemData.ReportingCategory.CategoryData.Name
-					} else if extra := itemData.ReportingCategory.GetExtraProperties(); extra != nil {
-						if name, ok := extra["name"].(string); ok {
-							meta.reportingCategoryName = name
-						}
-					}
-				}
-				if len(itemData.ImageIDs) > 0 {
-					meta.imageIDs = itemData.ImageIDs
-				}
-				itemsMeta[obj.Item.ID] = meta
-			}
-		case "ITEM_VARIATION":
-			if obj.ItemVariation != nil && obj.ItemVariation.ItemVariationData != nil {
-				vData := obj.ItemVariation.ItemVariationData
-				meta := variationMeta{}
-				if vData.ItemID != nil {
-					meta.itemID = *vData.ItemID
-				}
-				if vData.Name != nil {
-					meta.variationName = *vData.Name
-				}
-				if vData.Sku != nil && *vData.Sku != "" {
-					meta.sku = *vData.Sku
-				} else {
-					meta.sku = obj.ItemVariation.ID
-				}
-				if obj.ItemVariation.ImageID != nil {
-					meta.imageID = *obj.ItemVariation.ImageID
-				}
-				variationDetails[obj.ItemVariation.ID] = meta
-			}
-		}
-	}
-
-	for variationID, stock := range variationCounts {
-		meta := variationDetails[variationID]
-		parent := itemsMeta[meta.itemID]
-
-		name := parent.name
-		if name == "" {
-			name = meta.variationName
-		}
-		if name == "" {
-			name = variationID
-		}
-
-		// Prefer variation image, then item image list.
-		imageURL := ""
-		if meta.imageID != "" {
-			imageURL = imageURLs[meta.imageID]
-		}
-		if imageURL == "" && len(parent.imageIDs) > 0 {
-			if url, ok := imageURLs[parent.imageIDs[0]]; ok {
-				imageURL = url
-			}
-		}
-
-		categoryName := ""
-		if parent.categoryID != "" {
-			categoryName = categoryNames[parent.categoryID]
-		} else if parent.categoryName != "" {
-			categoryName = parent.categoryName
-		}
-
-		reportingCategory := parent.reportingCategoryName
-		if reportingCategory == "" && parent.reportingCategoryID != "" {
-			reportingCategory = categoryNames[parent.reportingCategoryID]
-			if reportingCategory == "" {
-				reportingCategory = parent.reportingCategoryID
-			}
-		}
-
-		displayCategory := categoryName
-		if displayCategory == "" {
-			displayCategory = reportingCategory
-		}
-
-		items = append(items, models.InventoryItem{
-			ID:                variationID,
-			Name:              name,
-			Description:       parent.desc,
-			SKU:               meta.sku,
-			CurrentStock:      stock,
-			ImageURL:          imageURL,
-			Category:          displayCategory,
-			ReportingCategory: reportingCategory,
-		})
-	}
-
-	log.Printf("Loaded %d inventory items from Square", len(items))
-
-	return items
-}
-
-func UpdateSampleInventoryItem(path string, sku string, update *models.InventoryItemUpdate) (*models.InventoryItem, error) {
-	if update == nil {
-		return nil, errors.New("update is required")
-	}
-
-	if sku == "" {
-		return nil, errors.New("sku is required")
-	}
-
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-
-	var items []models.InventoryItem
-	if err := json.Unmarshal(data, &items); err != nil {
 		return nil, err
 	}
 
-	var updatedItem models.InventoryItem
-	itemUpdated := false
-	for i := range items {
-		if items[i].SKU == sku {
-			items[i].ApplyUpdate(update)
-			updatedItem = items[i]
-			itemUpdated = true
-			break
-		}
-	}
-
-	if !itemUpdated {
+	if objectResp.Object == nil {
 		return nil, ErrInventoryItemNotFound
 	}
 
-	updatedData, err := json.MarshalIndent(items, "", "  ")
-	if err != nil {
-		return nil, err
-	}
-
-	if err := os.WriteFile(path, updatedData, 0644); err != nil {
-		return nil, err
-	}
-
-	return &updatedItem, nil
+	return objectResp.Object, nil
 }
 
-func UpdateInventoryItem(path string, sku string, update *models.InventoryItemUpdate) (*models.InventoryItem, error) {
-	if update == nil {
-		return nil, errors.New("update is required")
-	}
-
-	if sku == "" {
-		return nil, errors.New("sku is required")
-	}
-
-	if update.CurrentStock == nil {
-		return nil, errors.New("currentStock is required for inventory update")
-	}
-
+// deleteCatalogObject deletes a catalog object and the objects that depend on it, e.g. an
+// item's variations, and returns the IDs of everything deleted.
+func deleteCatalogObject(ctx context.Context, objectID string) ([]string, error) {
 	sqClient := client.SquareClient
 	if sqClient == nil {
-		return nil, errors.New("square client is not initialized")
+		return nil, errSquareClientNotInitialized
 	}
 
-	ctx := context.Background()
-
-	// Fetch catalog to map SKU -> variation and enrich response.
-	catalogObjects, err := fetchAllCatalogObjects(ctx)
+	deleteResp, err := sqClient.Catalog.Object.Delete(ctx, &catalog.DeleteObjectRequest{ObjectID: objectID})
 	if err != nil {
+		var apiErr *core.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			return nil, ErrInventoryItemNotFound
+		}
 		return nil, err
 	}
 
-	type itemMeta struct {
-		name                  string
-		desc                  string
-		categoryID            string
-		categoryName          string
-		reportingCategoryID   string
-		reportingCategoryName string
-		imageIDs              []string
-	}
+	return deleteResp.DeletedObjectIDs, nil
+}
 
-	type variationMeta struct {
-		itemID        string
-		variationName string
-		sku           string
-		imageID       string
+// upsertCatalogObjects creates or replaces the objects in one all-or-nothing batch and returns
+// them as stored by Square. Objects with a stale version fail the batch with ErrCatalogConflict.
+func upsertCatalogObjects(ctx context.Context, objects []*square.CatalogObject) ([]*square.CatalogObject, error) {
+	sqClient := client.SquareClient
+	if sqClient == nil {
+		return nil, errSquareClientNotInitialized
 	}
 
-	imageURLs := map[string]string{}
-	categoryNames := map[string]string{}
-	itemsMeta := map[string]itemMeta{}
-	variationDetails := map[string]variationMeta{}
-
-	for _, obj := range catalogObjects {
-		if obj == nil {
-			continue
-		}
-
-		switch obj.GetType() {
-		case "IMAGE":
-			if obj.Image != nil && obj.Image.ImageData != nil && obj.Image.ImageData.URL != nil {
-				imageURLs[obj.Image.ID] = *obj.Image.ImageData.URL
-			}
-		case "CATEGORY":
-			if obj.Category != nil && obj.Category.CategoryData != nil && obj.Category.CategoryData.Name != nil && obj.Category.ID != nil {
-				categoryNames[*obj.Category.ID] = *obj.Category.CategoryData.Name
-			}
-		case "ITEM":
-			if obj.Item != nil && obj.Item.ItemData != nil {
-				itemData := obj.Item.ItemData
-				meta := itemMeta{}
-				if itemData.Name != nil {
-					meta.name = *itemData.Name
-				}
-				if itemData.Description != nil {
-					meta.desc = *itemData.Description
-				}
-				if itemData.CategoryID != nil {
-					meta.categoryID = *itemData.CategoryID
-				} else if len(itemData.Categories) > 0 && itemData.Categories[0] != nil && itemData.Categories[0].CategoryData != nil && itemData.Categories[0].CategoryData.Name != nil {
-					if name := itemData.Categories[0].CategoryData.GetName(); name != nil {
-						meta.categoryName = *name
-					}
-				}
-				if itemData.ReportingCategory != nil {
-					if itemData.ReportingCategory.ID != nil {
-						meta.reportingCategoryID = *itemData.ReportingCategory.ID
-					}
-					if itemData.ReportingCategory.CategoryData != nil && itemData.ReportingCategory.CategoryData.Name != nil {
-						meta.reportingCategoryName = *itemData.ReportingCategory.CategoryData.Name
-					} else if extra := itemData.ReportingCategory.GetExtraProperties(); extra != nil {
-						if name, ok := extra["name"].(string); ok {
-							meta.reportingCategoryName = name
-						}
-					}
-				}
-				if len(itemData.ImageIDs) > 0 {
-					meta.imageIDs = itemData.ImageIDs
-				}
-				itemsMeta[obj.Item.ID] = meta
-			}
-		case "ITEM_VARIATION":
-			if obj.ItemVariation != nil && obj.ItemVariation.ItemVariationData != nil {
-				vData := obj.ItemVariation.ItemVariationData
-				meta := variationMeta{}
-				if vData.ItemID != nil {
-					meta.itemID = *vData.ItemID
-				}
-				if vData.Name != nil {
-					meta.variationName = *vData.Name
-				}
-				if vData.Sku != nil && *vData.Sku != "" {
-					meta.sku = *vData.Sku
-				} else {
-					meta.sku = obj.ItemVariation.ID
-				}
-				if obj.ItemVariation.ImageID != nil {
-					meta.imageID = *obj.ItemVariation.ImageID
+	upsertReq := &square.BatchUpsertCatalogObjectsRequest{
+		IdempotencyKey: uuid.NewString(),
+		Batches:        []*square.CatalogObjectBatch{{Objects: objects}},
+	}
+
+	variationIDs := []string{}
+	for _, obj := range objects {
+		switch {
+		case obj.ItemVariation != nil:
+			variationIDs = append(variationIDs, obj.ItemVariation.ID)
+		case obj.Item != nil && obj.Item.ItemData != nil:
+			for _, variation := range obj.Item.ItemData.Variations {
+				if variation.ItemVariation != nil {
+					variationIDs = append(variationIDs, variation.ItemVariation.ID)
 				}
-				variationDetails[obj.ItemVariation.ID] = meta
 			}
 		}
 	}
+	audit.NoteIdempotencyKey(ctx, upsertReq.IdempotencyKey, variationIDs...)
 
-	// Find the variation ID for this SKU.
-	var targetVariationID string
-	for variationID, meta := range variationDetails {
-		if meta.sku == sku {
-			targetVariationID = variationID
-			break
+	upsertResp, err := sqClient.Catalog.BatchUpsert(ctx, upsertReq)
+	if err != nil {
+		if hasSquareErrorCode(err, errorCodeVersionMismatch) {
+			return nil, ErrCatalogConflict
 		}
+		return nil, err
 	}
 
-	if targetVariationID == "" {
-		return nil, ErrInventoryItemNotFound
-	}
+	return upsertResp.Objects, nil
+}
 
-	// Fetch current count for that variation to compute delta.
-	countReq := &square.BatchGetInventoryCountsRequest{
-		CatalogObjectIDs: []string{targetVariationID},
-		LocationIDs:      []string{config.SquareLocationID},
-		States:           []square.InventoryState{square.InventoryStateInStock},
+// errorCodeVersionMismatch is what Square returns for catalog writes with a stale object
+// version. The SDK has no constant for it.
+const errorCodeVersionMismatch square.ErrorCode = "VERSION_MISMATCH"
+
+// hasSquareErrorCode reports whether err is a Square API error response containing code.
+func hasSquareErrorCode(err error, code square.ErrorCode) bool {
+	var apiErr *core.APIError
+	if !errors.As(err, &apiErr) || apiErr.Unwrap() == nil {
+		return false
 	}
 
-	countResp, err := sqClient.Inventory.BatchGetCounts(ctx, countReq)
-	if err != nil {
-		return nil, err
+	var body struct {
+		Errors []*square.Error `json:"errors"`
+	}
+	if err := json.Unmarshal([]byte(apiErr.Unwrap().Error()), &body); err != nil {
+		return false
 	}
 
-	currentQty := 0
-	if len(countResp.Counts) > 0 && countResp.Counts[0] != nil && countResp.Counts[0].Quantity != nil {
-		parsed, err := strconv.ParseFloat(*countResp.Counts[0].Quantity, 64)
-		if err == nil {
-			currentQty = int(math.Round(parsed))
+	for _, sqErr := range body.Errors {
+		if sqErr != nil && sqErr.Code == code {
+			return true
 		}
 	}
 
-	newQty := *update.CurrentStock
-	delta := newQty - currentQty
-
-	if delta == 0 {
-		// Nothing to change; return current state.
-		meta := variationDetails[targetVariationID]
-		parent := itemsMeta[meta.itemID]
-
-		name := parent.name
-		if name == "" {
-			name = meta.variationName
-		}
-		if name == "" {
-			name = targetVariationID
-		}
+	return false
+}
 
-		imageURL := ""
-		if meta.imageID != "" {
-			imageURL = imageURLs[meta.imageID]
-		}
-		if imageURL == "" && len(parent.imageIDs) > 0 {
-			if url, ok := imageURLs[parent.imageIDs[0]]; ok {
-				imageURL = url
-			}
-		}
+// maxChangesPerRequest is the most changes Square accepts in one BatchCreateChanges request.
+const maxChangesPerRequest = 100
 
-		categoryName := ""
-		if parent.categoryID != "" {
-			categoryName = categoryNames[parent.categoryID]
-		} else if parent.categoryName != "" {
-			categoryName = parent.categoryName
-		}
+// adjustInventoryCount records a signed stock delta for a variation and returns the resulting
+// IN_STOCK count and its version. The reason decides which states the quantity moves between.
+func adjustInventoryCount(ctx context.Context, locationID, variationID string, delta int, reason AdjustmentReason) (int, string, error) {
+	change, err := newAdjustmentChange(locationID, variationID, delta, reason, time.Now())
+	if err != nil {
+		return 0, "", err
+	}
 
-		reportingCategory := parent.reportingCategoryName
-		if reportingCategory == "" && parent.reportingCategoryID != "" {
-			reportingCategory = categoryNames[parent.reportingCategoryID]
-			if reportingCategory == "" {
-				reportingCategory = parent.reportingCategoryID
-			}
-		}
+	return submitInventoryChange(ctx, locationID, variationID, change)
+}
 
-		displayCategory := categoryName
-		if displayCategory == "" {
-			displayCategory = reportingCategory
-		}
+// recordPhysicalCount records that a variation was counted at quantity at countedAt, which
+// Square shows in its history as a count rather than as sales or receipts. It returns the
+// resulting IN_STOCK count and its version.
+func recordPhysicalCount(ctx context.Context, locationID, variationID string, quantity int, countedAt time.Time) (int, string, error) {
+	change := newPhysicalCountChange(locationID, variationID, quantity, countedAt)
+	return submitInventoryChange(ctx, locationID, variationID, change)
+}
 
-		return &models.InventoryItem{
-			ID:                targetVariationID,
-			Name:              name,
-			Description:       parent.desc,
-			SKU:               meta.sku,
-			CurrentStock:      currentQty,
-			ImageURL:          imageURL,
-			Category:          displayCategory,
-			ReportingCategory: reportingCategory,
-		}, nil
+func newAdjustmentChange(locationID, variationID string, delta int, reason AdjustmentReason, occurredAt time.Time) (*square.InventoryChange, error) {
+	transition, err := reason.transition(delta)
+	if err != nil {
+		return nil, err
 	}
 
 	absDelta := int(math.Abs(float64(delta)))
 	quantityStr := strconv.Itoa(absDelta)
-	fromState := square.InventoryStateNone
-	toState := square.InventoryStateInStock
-
-	if delta < 0 {
-		fromState = square.InventoryStateInStock
-		toState = square.InventoryStateSold
-	}
+	fromState := transition.from
+	toState := transition.to
 
 	adjustment := &square.InventoryAdjustment{
-		CatalogObjectID: square.String(targetVariationID),
-		LocationID:      square.String(config.SquareLocationID),
+		CatalogObjectID: square.String(variationID),
+		LocationID:      square.String(locationID),
 		FromState:       &fromState,
 		ToState:         &toState,
 		Quantity:        square.String(quantityStr),
-		OccurredAt:      square.String(time.Now().UTC().Format(time.RFC3339)),
+		OccurredAt:      square.String(occurredAt.UTC().Format(time.RFC3339)),
 	}
 
 	changeType := square.InventoryChangeTypeAdjustment
-	change := &square.InventoryChange{
+	return &square.InventoryChange{
 		Type:       &changeType,
 		Adjustment: adjustment,
-	}
+	}, nil
+}
 
-	batchReq := &square.BatchChangeInventoryRequest{
-		IdempotencyKey: uuid.NewString(),
-		Changes:        []*square.InventoryChange{change},
+func newPhysicalCountChange(locationID, variationID string, quantity int, countedAt time.Time) *square.InventoryChange {
+	state := square.InventoryStateInStock
+	physicalCount := &square.InventoryPhysicalCount{
+		CatalogObjectID: square.String(variationID),
+		LocationID:      square.String(locationID),
+		State:           &state,
+		Quantity:        square.String(strconv.Itoa(quantity)),
+		OccurredAt:      square.String(countedAt.UTC().Format(time.RFC3339)),
 	}
 
-	if _, err := sqClient.Inventory.BatchCreateChanges(ctx, batchReq); err != nil {
-		return nil, err
+	changeType := square.InventoryChangeTypePhysicalCount
+	return &square.InventoryChange{
+		Type:          &changeType,
+		PhysicalCount: physicalCount,
 	}
+}
 
-	// Return the updated item with new stock.
-	meta := variationDetails[targetVariationID]
-	parent := itemsMeta[meta.itemID]
+// submitInventoryChange sends a single inventory change and returns the resulting IN_STOCK
+// count of the variation and its version.
+func submitInventoryChange(ctx context.Context, locationID, variationID string, change *square.InventoryChange) (int, string, error) {
+	counts, err := submitInventoryChanges(ctx, locationID, []*square.InventoryChange{change})
+	if err != nil {
+		return 0, "", err
+	}
 
-	name := parent.name
-	if name == "" {
-		name = meta.variationName
+	if counts[variationID] != nil {
+		qty, version := counts[variationID].inStock()
+		return qty, version, nil
 	}
-	if name == "" {
-		name = targetVariationID
+
+	return fetchInventoryCount(ctx, locationID, variationID)
+}
+
+// submitInventoryChanges sends up to maxChangesPerRequest changes, which Square applies all or
+// nothing, and returns the resulting IN_STOCK counts at locationID keyed by variation ID.
+func submitInventoryChanges(ctx context.Context, locationID string, changes []*square.InventoryChange) (map[string]*locationCount, error) {
+	sqClient := client.SquareClient
+	if sqClient == nil {
+		return nil, errSquareClientNotInitialized
 	}
 
-	imageURL := ""
-	if meta.imageID != "" {
-		imageURL = imageURLs[meta.imageID]
+	batchReq := &square.BatchChangeInventoryRequest{
+		IdempotencyKey: uuid.NewString(),
+		Changes:        changes,
 	}
-	if imageURL == "" && len(parent.imageIDs) > 0 {
-		if url, ok := imageURLs[parent.imageIDs[0]]; ok {
-			imageURL = url
+
+	variationIDs := make([]string, 0, len(changes))
+	for _, change := range changes {
+		switch {
+		case change.Adjustment != nil && change.Adjustment.CatalogObjectID != nil:
+			variationIDs = append(variationIDs, *change.Adjustment.CatalogObjectID)
+		case change.PhysicalCount != nil && change.PhysicalCount.CatalogObjectID != nil:
+			variationIDs = append(variationIDs, *change.PhysicalCount.CatalogObjectID)
 		}
 	}
+	audit.NoteIdempotencyKey(ctx, batchReq.IdempotencyKey, variationIDs...)
 
-	categoryName := ""
-	if parent.categoryID != "" {
-		categoryName = categoryNames[parent.categoryID]
-	} else if parent.categoryName != "" {
-		categoryName = parent.categoryName
+	batchResp, err := sqClient.Inventory.BatchCreateChanges(ctx, batchReq)
+	if err != nil {
+		return nil, err
 	}
 
-	reportingCategory := parent.reportingCategoryName
-	if reportingCategory == "" && parent.reportingCategoryID != "" {
-		reportingCategory = categoryNames[parent.reportingCategoryID]
-		if reportingCategory == "" {
-			reportingCategory = parent.reportingCategoryID
+	// Square returns the resulting counts for every object in the request.
+	counts := map[string]*locationCount{}
+	for _, count := range batchResp.Counts {
+		if count == nil || count.CatalogObjectID == nil || count.Quantity == nil {
+			continue
+		}
+		if count.LocationID != nil && *count.LocationID != locationID {
+			continue
+		}
+		if count.State != nil && *count.State != square.InventoryStateInStock {
+			continue
 		}
-	}
 
-	displayCategory := categoryName
-	if displayCategory == "" {
-		displayCategory = reportingCategory
+		qty, err := parseQuantity(*count.Quantity)
+		if err != nil {
+			log.Printf("ERROR: Could not parse quantity for catalog object %s: %v", *count.CatalogObjectID, err)
+			continue
+		}
+
+		counts[*count.CatalogObjectID] = &locationCount{
+			states:  stateCounts{square.InventoryStateInStock: qty},
+			version: stockVersion(count.CalculatedAt, qty),
+		}
 	}
 
-	return &models.InventoryItem{
-		ID:                targetVariationID,
-		Name:              name,
-		Description:       parent.desc,
-		SKU:               meta.sku,
-		CurrentStock:      newQty,
-		ImageURL:          imageURL,
-		Category:          displayCategory,
-		ReportingCategory: reportingCategory,
-	}, nil
+	return counts, nil
 }
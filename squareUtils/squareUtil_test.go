@@ -0,0 +1,99 @@
+package squareUtils
+
+import (
+	"aoa-inventory/squareUtils/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	square "github.com/square/square-go-sdk"
+)
+
+func TestAdjustInventoryItem(t *testing.T) {
+	backend, _ := newFakeBackend(t)
+	ctx := context.Background()
+
+	item, err := backend.AdjustInventoryItem(ctx, "", "LAT-001", 3, ReasonReceived)
+	if err != nil {
+		t.Fatalf("AdjustInventoryItem(+3): %v", err)
+	}
+	if item.CurrentStock != 13 {
+		t.Errorf("stock after +3 = %d, want 13", item.CurrentStock)
+	}
+
+	item, err = backend.AdjustInventoryItem(ctx, "", "LAT-001", -5, "")
+	if err != nil {
+		t.Fatalf("AdjustInventoryItem(-5): %v", err)
+	}
+	if item.CurrentStock != 8 {
+		t.Errorf("stock after -5 = %d, want 8", item.CurrentStock)
+	}
+
+	if _, err := backend.AdjustInventoryItem(ctx, "", "LAT-001", 2, ReasonSold); !errors.Is(err, ErrInvalidAdjustment) {
+		t.Errorf("selling stock upwards: err = %v, want ErrInvalidAdjustment", err)
+	}
+	if _, err := backend.AdjustInventoryItem(ctx, "", "LAT-001", 0, ""); !errors.Is(err, ErrInvalidAdjustment) {
+		t.Errorf("zero delta: err = %v, want ErrInvalidAdjustment", err)
+	}
+}
+
+func TestAdjustInventoryItemEveryReason(t *testing.T) {
+	backend, _ := newFakeBackend(t)
+	ctx := context.Background()
+
+	stock := 10
+	for _, step := range []struct {
+		transitions map[AdjustmentReason]stateTransition
+		delta       int
+	}{{increaseTransitions, 1}, {decreaseTransitions, -1}} {
+		for reason := range step.transitions {
+			item, err := backend.AdjustInventoryItem(ctx, "", "LAT-001", step.delta, reason)
+			if err != nil {
+				t.Errorf("AdjustInventoryItem(%d, %q): %v", step.delta, reason, err)
+				continue
+			}
+			stock += step.delta
+			if item.CurrentStock != stock {
+				t.Errorf("stock after %d for %q = %d, want %d", step.delta, reason, item.CurrentStock, stock)
+			}
+		}
+	}
+}
+
+func TestSubmitInventoryChangesRejectsUnsupportedTransition(t *testing.T) {
+	_, server := newFakeBackend(t)
+
+	change, err := newAdjustmentChange(server.LocationID, "VAR_LATTE", -1, ReasonWaste, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	none := square.InventoryStateNone
+	change.Adjustment.ToState = &none
+
+	if _, err := submitInventoryChanges(context.Background(), server.LocationID, []*square.InventoryChange{change}); err == nil {
+		t.Error("err = nil, want Square to reject IN_STOCK -> NONE")
+	}
+}
+
+func TestUpdateInventoryItemRecordsPhysicalCount(t *testing.T) {
+	backend, _ := newFakeBackend(t)
+	countedAt := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+
+	item := updateItem(t, backend, "LAT-001", &models.InventoryItemUpdate{CurrentStock: ptr(7), CountedAt: &countedAt})
+	if item.CurrentStock != 7 {
+		t.Errorf("returned stock = %d, want 7", item.CurrentStock)
+	}
+	if got := getItem(t, backend, "LAT-001", "").CurrentStock; got != 7 {
+		t.Errorf("stock read back = %d, want 7", got)
+	}
+
+	history, err := backend.InventoryHistory(context.Background(), "LAT-001", HistoryQuery{})
+	if err != nil {
+		t.Fatalf("InventoryHistory: %v", err)
+	}
+	last := history.Changes[len(history.Changes)-1]
+	if last.Type != "PHYSICAL_COUNT" || last.Quantity != 7 || last.OccurredAt != countedAt.Format(time.RFC3339) {
+		t.Errorf("last change = %+v, want a physical count of 7 at %s", last, countedAt.Format(time.RFC3339))
+	}
+}
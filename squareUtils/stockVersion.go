@@ -0,0 +1,48 @@
+package squareUtils
+
+import (
+	"aoa-inventory/squareUtils/models"
+	"errors"
+	"hash/fnv"
+	"strconv"
+)
+
+var ErrStockConflict = errors.New("stock changed since it was read")
+
+// StockConflictError is returned when an update's expected stock or version no longer matches.
+// Item holds the current state so the client can retry against it.
+type StockConflictError struct {
+	Item *models.InventoryItem
+}
+
+func (e *StockConflictError) Error() string {
+	return ErrStockConflict.Error()
+}
+
+func (e *StockConflictError) Unwrap() error {
+	return ErrStockConflict
+}
+
+// stockVersion identifies an IN_STOCK count by when it was last calculated and its quantity, so
+// it changes whenever the count does. calculatedAt is nil for counts that were never recorded.
+func stockVersion(calculatedAt *string, quantity int) string {
+	hash := fnv.New64a()
+	if calculatedAt != nil {
+		hash.Write([]byte(*calculatedAt))
+	}
+	hash.Write([]byte("|" + strconv.Itoa(quantity)))
+	return strconv.FormatUint(hash.Sum64(), 36)
+}
+
+// checkExpectedStock compares the update's preconditions against the current item.
+func checkExpectedStock(update *models.InventoryItemUpdate, current models.InventoryItem) error {
+	if update.ExpectedCurrentStock != nil && *update.ExpectedCurrentStock != current.CurrentStock {
+		return &StockConflictError{Item: &current}
+	}
+
+	if update.ExpectedVersion != nil && *update.ExpectedVersion != current.Version {
+		return &StockConflictError{Item: &current}
+	}
+
+	return nil
+}
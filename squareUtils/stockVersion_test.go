@@ -0,0 +1,37 @@
+package squareUtils
+
+import (
+	"aoa-inventory/squareUtils/models"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestUpdateInventoryItemConflicts(t *testing.T) {
+	backend, _ := newFakeBackend(t)
+
+	stale := getItem(t, backend, "LAT-001", "")
+	updateItem(t, backend, "LAT-001", &models.InventoryItemUpdate{CurrentStock: ptr(8)})
+
+	tests := []struct {
+		name   string
+		update models.InventoryItemUpdate
+	}{
+		{"expected stock", models.InventoryItemUpdate{CurrentStock: ptr(5), ExpectedCurrentStock: ptr(10)}},
+		{"expected version", models.InventoryItemUpdate{CurrentStock: ptr(5), ExpectedVersion: ptr(stale.Version)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := backend.UpdateInventoryItem(context.Background(), "", "LAT-001", &tt.update)
+
+			var conflict *StockConflictError
+			if !errors.As(err, &conflict) {
+				t.Fatalf("err = %v, want a StockConflictError", err)
+			}
+			if conflict.Item.CurrentStock != 8 {
+				t.Errorf("conflict item stock = %d, want the current 8", conflict.Item.CurrentStock)
+			}
+		})
+	}
+}
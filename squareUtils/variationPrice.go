@@ -0,0 +1,138 @@
+package squareUtils
+
+import (
+	"aoa-inventory/squareUtils/models"
+	"fmt"
+	"slices"
+	"strings"
+
+	square "github.com/square/square-go-sdk"
+)
+
+// variationPrice is a price as Square stores it, on a variation or in a location override.
+type variationPrice struct {
+	pricingType square.CatalogPricingType
+	money       *square.Money
+}
+
+func (p variationPrice) isSet() bool {
+	return p.pricingType != "" || p.money != nil
+}
+
+// apiPrice converts the price to the API model, or nil when none is set.
+func (p variationPrice) apiPrice() *models.Price {
+	if !p.isSet() {
+		return nil
+	}
+
+	price := &models.Price{PricingType: string(p.pricingType)}
+	if p.money != nil {
+		price.Amount = p.money.Amount
+		if p.money.Currency != nil {
+			price.Currency = string(*p.money.Currency)
+		}
+	}
+	if price.PricingType == "" {
+		price.PricingType = string(square.CatalogPricingTypeFixedPricing)
+	}
+	if price.PricingType == string(square.CatalogPricingTypeVariablePricing) {
+		price.Amount, price.Currency = nil, ""
+	}
+	return price
+}
+
+// price returns the variation's price at the location, using its override there if it has one.
+// An empty locationID returns the base price.
+func (idx *catalogIndex) price(variationID, locationID string) *models.Price {
+	meta := idx.variationDetails[variationID]
+	if override, ok := meta.locationPrices[locationID]; ok && override.isSet() {
+		return override.apiPrice()
+	}
+	return meta.price.apiPrice()
+}
+
+// locationPrice returns the variation's override price at the location, or nil when it has none.
+func (idx *catalogIndex) locationPrice(variationID, locationID string) *models.Price {
+	return idx.variationDetails[variationID].locationPrices[locationID].apiPrice()
+}
+
+// checkPriceUpdate rejects negative prices.
+func checkPriceUpdate(update *models.PriceUpdate) error {
+	if update != nil && update.Amount != nil && *update.Amount < 0 {
+		return fmt.Errorf("%w: price cannot be negative", ErrInvalidItemUpdate)
+	}
+	return nil
+}
+
+// priceUnchanged reports whether the update would set the price the item already has, as when
+// a client sends back the item it read.
+func priceUnchanged(update *models.PriceUpdate, current *models.Price) bool {
+	if current == nil {
+		return false
+	}
+	if update.Amount == nil {
+		return current.PricingType == string(square.CatalogPricingTypeVariablePricing)
+	}
+	return current.Amount != nil && *current.Amount == *update.Amount &&
+		(update.Currency == "" || strings.EqualFold(update.Currency, current.Currency))
+}
+
+// setVariationPrice writes the price to the variation, or to its override at locationID when
+// the update is for that location only. A nil money switches to variable pricing, or removes
+// the override's price.
+func setVariationPrice(variationData *square.CatalogItemVariation, locationID string, atLocation bool, money *square.Money) {
+	pricingType := square.CatalogPricingTypeFixedPricing.Ptr()
+	if money == nil {
+		pricingType = square.CatalogPricingTypeVariablePricing.Ptr()
+	}
+
+	if !atLocation {
+		variationData.PricingType = pricingType
+		variationData.PriceMoney = money
+		return
+	}
+
+	if money == nil {
+		if override := locationOverride(variationData, locationID, false); override != nil {
+			override.PricingType = nil
+			override.PriceMoney = nil
+		}
+		return
+	}
+
+	override := locationOverride(variationData, locationID, true)
+	override.PricingType = pricingType
+	override.PriceMoney = money
+}
+
+// locationOverride returns the variation's override for the location, adding an empty one if
+// create is set and there is none yet.
+func locationOverride(variationData *square.CatalogItemVariation, locationID string, create bool) *square.ItemVariationLocationOverrides {
+	i := slices.IndexFunc(variationData.LocationOverrides, func(override *square.ItemVariationLocationOverrides) bool {
+		return override != nil && override.LocationID != nil && *override.LocationID == locationID
+	})
+	if i >= 0 {
+		return variationData.LocationOverrides[i]
+	}
+	if !create {
+		return nil
+	}
+
+	override := &square.ItemVariationLocationOverrides{LocationID: square.String(locationID)}
+	variationData.LocationOverrides = append(variationData.LocationOverrides, override)
+	return override
+}
+
+// squareMoney converts an API amount, defaulting its currency to the given one.
+func squareMoney(amount int64, currencyCode, fallback string) (*square.Money, error) {
+	if currencyCode == "" {
+		currencyCode = fallback
+	}
+
+	currency, err := square.NewCurrencyFromString(strings.ToUpper(currencyCode))
+	if err != nil {
+		return nil, fmt.Errorf("unknown currency %s", currencyCode)
+	}
+
+	return &square.Money{Amount: square.Int64(amount), Currency: currency.Ptr()}, nil
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// TokenBucket allows rate events per second on average, with bursts of up to burst events.
+type TokenBucket struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket returns a full bucket.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	return &TokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// Reserve takes a token and returns how long the caller must wait before using it. When the wait
+// would exceed maxWait no token is taken, and the wait is returned with false.
+func (b *TokenBucket) Reserve(maxWait time.Duration) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(time.Now())
+
+	// Tokens may go negative; the debt is what later callers wait for.
+	wait := b.delay()
+	if wait > maxWait {
+		return wait, false
+	}
+
+	b.tokens--
+	return wait, true
+}
+
+// Delay returns how long until a token is available, without taking one.
+func (b *TokenBucket) Delay() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(time.Now())
+	return b.delay()
+}
+
+// Take takes a token however long the wait, leaving later callers to wait for the debt.
+func (b *TokenBucket) Take() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(time.Now())
+	b.tokens--
+}
+
+// Full reports whether the bucket has refilled completely, i.e. it has been idle long enough to
+// be dropped and recreated without changing behaviour.
+func (b *TokenBucket) Full() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(time.Now())
+	return b.tokens >= b.burst
+}
+
+func (b *TokenBucket) refill(now time.Time) {
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+func (b *TokenBucket) delay() time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucketReserve(t *testing.T) {
+	bucket := NewTokenBucket(1, 2)
+
+	for i := range 2 {
+		if wait, ok := bucket.Reserve(0); !ok || wait != 0 {
+			t.Fatalf("reserve %d = %v, %t; want a token right away", i+1, wait, ok)
+		}
+	}
+
+	wait, ok := bucket.Reserve(0)
+	if ok {
+		t.Fatal("reserve past the burst succeeded without waiting")
+	}
+	if wait <= 0 || wait > time.Second {
+		t.Errorf("wait = %v, want up to a second", wait)
+	}
+
+	// A refused reservation takes no token, so the wait does not grow.
+	if again, _ := bucket.Reserve(0); again > wait {
+		t.Errorf("wait grew from %v to %v after a refused reservation", wait, again)
+	}
+
+	// A reservation allowed to wait goes into debt, which later callers wait for.
+	if _, ok := bucket.Reserve(time.Second); !ok {
+		t.Fatal("reserve with a second to wait was refused")
+	}
+	if later, _ := bucket.Reserve(0); later <= time.Second {
+		t.Errorf("wait after going into debt = %v, want over a second", later)
+	}
+}
+
+func TestTokenBucketRefills(t *testing.T) {
+	bucket := NewTokenBucket(100, 1)
+
+	if _, ok := bucket.Reserve(0); !ok {
+		t.Fatal("first reserve was refused")
+	}
+	if bucket.Full() {
+		t.Error("bucket is full right after a reservation")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if !bucket.Full() {
+		t.Error("bucket is not full after refilling")
+	}
+	if _, ok := bucket.Reserve(0); !ok {
+		t.Error("reserve after refilling was refused")
+	}
+}
+
+func TestTokenBucketDelayAndTake(t *testing.T) {
+	bucket := NewTokenBucket(1, 1)
+
+	if delay := bucket.Delay(); delay != 0 {
+		t.Errorf("delay of a full bucket = %v, want 0", delay)
+	}
+	if !bucket.Full() {
+		t.Error("Delay took a token")
+	}
+
+	bucket.Take()
+	bucket.Take()
+	if delay := bucket.Delay(); delay <= time.Second || delay > 2*time.Second {
+		t.Errorf("delay after taking two tokens = %v, want between one and two seconds", delay)
+	}
+}